@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd
+
+package updater
+
+import "os"
+
+// dirAccessible reports whether dir is writable without writing to it. Only
+// the read-only permission bits are checked, e.g., the read-only attribute on
+// Windows.
+func dirAccessible(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().Perm()&0200 != 0
+}
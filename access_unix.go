@@ -0,0 +1,11 @@
+//go:build linux || darwin || freebsd
+
+package updater
+
+import "golang.org/x/sys/unix"
+
+// dirAccessible reports whether the current user has write access to dir
+// without writing to it.
+func dirAccessible(dir string) bool {
+	return unix.Access(dir, unix.W_OK) == nil
+}
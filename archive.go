@@ -0,0 +1,525 @@
+package updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var ErrBinaryNotFoundInArchive = errors.New("No binary matched the name")
+
+var ErrArchiveTooLarge = errors.New("Archive exceeds the maximum extracted size")
+
+const defaultMaxExtractedBytes = 4 << 30
+
+// tarballExts are the supported tarball extensions.
+var tarballExts = []string{".tar.gz", ".tgz", ".tar.zst", ".tzst", ".tar.xz", ".txz", ".tar"}
+
+func isTarball(name string) bool {
+	lowerName := strings.ToLower(name)
+	for _, ext := range tarballExts {
+		if strings.HasSuffix(lowerName, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isZip(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".zip")
+}
+
+func isArchive(name string) bool {
+	return isTarball(name) || isZip(name)
+}
+
+// isGzipBinary reports whether a binary downloaded directly is gzip
+// compressed, e.g., myapp.gz.
+func isGzipBinary(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".gz") && !isTarball(name)
+}
+
+// uncompressedName is the name of a binary downloaded directly once
+// decompressed.
+func uncompressedName(name string) string {
+	if isGzipBinary(name) {
+		return name[:len(name)-len(".gz")]
+	}
+
+	return name
+}
+
+func unsupportedArchive(name string) error {
+	return fmt.Errorf("Error. Only %s or .zip archives are supported. Got %s", strings.Join(tarballExts, ", "), name)
+}
+
+// decompressTarball returns the tar stream of a tarball, compressed or not.
+func decompressTarball(name string, reader io.Reader) (io.ReadCloser, error) {
+	lowerName := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lowerName, ".tar.zst") || strings.HasSuffix(lowerName, ".tzst"):
+		decoder, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case strings.HasSuffix(lowerName, ".tar.xz") || strings.HasSuffix(lowerName, ".txz"):
+		decoder, err := xz.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(decoder), nil
+	case strings.HasSuffix(lowerName, ".tar"):
+		return io.NopCloser(reader), nil
+	default:
+		return gzip.NewReader(reader)
+	}
+}
+
+// extractLimit caps the bytes extracted from an archive, per entry and in
+// total, guarding against decompression bombs. Sizes declared by the archive
+// are checked upfront but the bytes actually read are what is enforced.
+type extractLimit struct {
+	entry     int64
+	total     int64
+	extracted int64
+}
+
+func (updater *Updater) newExtractLimit() *extractLimit {
+	total := updater.config.MaxExtractedBytes
+	if total <= 0 {
+		total = defaultMaxExtractedBytes
+	}
+	entry := updater.config.MaxEntryBytes
+	if entry <= 0 || entry > total {
+		entry = total
+	}
+
+	return &extractLimit{entry: entry, total: total}
+}
+
+// wrap limits the entry read from reader. size is the size declared by the
+// archive, or -1 when unknown.
+func (limit *extractLimit) wrap(name string, size int64, reader io.Reader) (io.Reader, error) {
+	if size > limit.entry {
+		return nil, fmt.Errorf("%w. %s is %d bytes but entries are limited to %d bytes", ErrArchiveTooLarge, name, size, limit.entry)
+	}
+	if size > limit.total-limit.extracted {
+		return nil, fmt.Errorf("%w. Extracting %s exceeds the limit of %d bytes", ErrArchiveTooLarge, name, limit.total)
+	}
+
+	return &limitedEntry{limit: limit, name: name, reader: reader, remaining: limit.entry}, nil
+}
+
+type limitedEntry struct {
+	limit     *extractLimit
+	name      string
+	reader    io.Reader
+	remaining int64
+}
+
+func (entry *limitedEntry) Read(p []byte) (int, error) {
+	n, err := entry.reader.Read(p)
+	entry.remaining -= int64(n)
+	entry.limit.extracted += int64(n)
+	if entry.remaining < 0 {
+		return n, fmt.Errorf("%w. %s exceeds the limit of %d bytes per entry", ErrArchiveTooLarge, entry.name, entry.limit.entry)
+	}
+	if entry.limit.extracted > entry.limit.total {
+		return n, fmt.Errorf("%w. Extracting %s exceeds the limit of %d bytes", ErrArchiveTooLarge, entry.name, entry.limit.total)
+	}
+
+	return n, err
+}
+
+// decompressBinary decompresses a gzip compressed binary next to src,
+// returning the path of the decompressed binary.
+func (updater *Updater) decompressBinary(name string, src string) (string, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(src)
+	defer file.Close()
+
+	uncompressedStream, err := gzip.NewReader(updater.fileProgress(src).wrap(file))
+	if err != nil {
+		return "", fmt.Errorf("Error decompressing %s. %w", name, err)
+	}
+	defer uncompressedStream.Close()
+
+	reader, err := updater.newExtractLimit().wrap(name, -1, uncompressedStream)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := extractFile(filepath.Dir(src), reader)
+	if err != nil {
+		return "", fmt.Errorf("Error decompressing %s. %w", name, err)
+	}
+
+	updater.logger().Debug("Decompressed binary", "name", name)
+	return path, nil
+}
+
+type extraction struct {
+	archiveName   string
+	binaryName    string
+	migrationName string
+	destination   string
+	binaryPath    string
+	binaryMode    os.FileMode
+	migrationPath string
+	checksums     []byte
+	limit         *extractLimit
+}
+
+func (updater *Updater) newExtraction(info *downloadInfo, src string) *extraction {
+	return &extraction{
+		archiveName:   info.archiveName,
+		binaryName:    info.binaryName,
+		migrationName: info.migrationName,
+		destination:   filepath.Dir(src),
+		limit:         updater.newExtractLimit(),
+	}
+}
+
+func extractFile(destination string, reader io.Reader) (string, error) {
+	path := filepath.Join(destination, uuid.NewString())
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create file. %w", err)
+	}
+
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("Failed to copy file. %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("Failed to close file. %w", err)
+	}
+
+	return path, nil
+}
+
+// extractEntry extracts the entry if it is the binary, the migration or the
+// checksum file. size is the size declared by the archive, or -1 when
+// unknown.
+func (updater *Updater) extractEntry(ex *extraction, name string, mode os.FileMode, size int64, reader io.Reader) error {
+	entryPath, err := archiveEntryPath(name)
+	if err != nil {
+		// Entries outside of the archive root are never extracted.
+		return nil
+	}
+	basename := filepath.Base(entryPath)
+
+	checksumFile := updater.config.ArchiveChecksumFile
+	isBinary := ex.binaryPath == "" && basename == ex.binaryName
+	isMigration := ex.migrationName != "" && ex.migrationPath == "" && basename == ex.migrationName
+	isChecksums := checksumFile != "" && ex.checksums == nil && basename == checksumFile
+	if !isBinary && !isMigration && !isChecksums {
+		return nil
+	}
+
+	reader, err = ex.limit.wrap(name, size, reader)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isBinary:
+		ex.binaryPath, err = extractFile(ex.destination, reader)
+		ex.binaryMode = mode
+	case isMigration:
+		ex.migrationPath, err = extractFile(ex.destination, reader)
+	default:
+		ex.checksums, err = io.ReadAll(reader)
+		if err != nil {
+			err = fmt.Errorf("Failed to read %s. %w", checksumFile, err)
+		}
+	}
+
+	return err
+}
+
+func (updater *Updater) extractionDone(ex *extraction) bool {
+	return ex.binaryPath != "" &&
+		(updater.config.ArchiveChecksumFile == "" || ex.checksums != nil) &&
+		(ex.migrationName == "" || ex.migrationPath != "")
+}
+
+func (updater *Updater) finishExtraction(ex *extraction, err error) (*stagedUpdate, error) {
+	if err == nil && ex.binaryPath == "" {
+		err = fmt.Errorf("Error extracting binary from %s. %w %s", ex.archiveName, ErrBinaryNotFoundInArchive, ex.binaryName)
+	}
+
+	if err == nil && ex.migrationName != "" && ex.migrationPath == "" {
+		err = fmt.Errorf("Error extracting migration from %s. No file matched the name %s", ex.archiveName, ex.migrationName)
+	}
+
+	if err == nil && updater.config.ArchiveChecksumFile != "" {
+		err = verifyArchiveChecksum(ex, updater.config.ArchiveChecksumFile, ex.binaryName)
+	}
+
+	staged := &stagedUpdate{path: ex.binaryPath, binaryPath: ex.binaryPath, migrationPath: ex.migrationPath, mode: ex.binaryMode}
+	if err != nil {
+		staged.remove()
+		return nil, err
+	}
+
+	updater.logger().Debug("Extracted binary", "archive", ex.archiveName, "binary", ex.binaryName, "migration", ex.migrationName)
+	return staged, nil
+}
+
+func (updater *Updater) extractZip(info *downloadInfo, src string) (*stagedUpdate, error) {
+	ex := updater.newExtraction(info, src)
+
+	uncompressedStream, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("ExtractZip: NewReader failed %w", err)
+	}
+	defer os.Remove(src)
+	defer uncompressedStream.Close()
+
+	progress := updater.zipProgress(uncompressedStream.File)
+	for _, f := range uncompressedStream.File {
+		if !f.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return updater.finishExtraction(ex, fmt.Errorf("ExtractZip: failed to open file %w", err))
+		}
+
+		err = updater.extractEntry(ex, f.Name, f.FileInfo().Mode(), int64(f.UncompressedSize64), progress.wrap(rc))
+		rc.Close()
+		if err != nil {
+			return updater.finishExtraction(ex, fmt.Errorf("ExtractZip: %w", err))
+		}
+
+		if updater.extractionDone(ex) {
+			break
+		}
+	}
+
+	return updater.finishExtraction(ex, nil)
+}
+
+func (updater *Updater) extractTarball(info *downloadInfo, src string) (*stagedUpdate, error) {
+	ex := updater.newExtraction(info, src)
+
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(src)
+	defer file.Close()
+
+	uncompressedStream, err := decompressTarball(info.archiveName, updater.fileProgress(src).wrap(file))
+	if err != nil {
+		return nil, fmt.Errorf("ExtractTarGz: NewReader failed %w", err)
+	}
+	defer uncompressedStream.Close()
+
+	tarReader := tar.NewReader(uncompressedStream)
+
+	for {
+		header, err := tarReader.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return updater.finishExtraction(ex, fmt.Errorf("ExtractTarGz: Next() failed: %w", err))
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		err = updater.extractEntry(ex, header.Name, header.FileInfo().Mode(), header.Size, tarReader)
+		if err != nil {
+			return updater.finishExtraction(ex, fmt.Errorf("ExtractTarGz: %w", err))
+		}
+
+		if updater.extractionDone(ex) {
+			break
+		}
+	}
+
+	return updater.finishExtraction(ex, nil)
+}
+
+// archiveEntryPath normalizes an archive entry name to a path relative to the
+// extraction directory. Leading ./ and . components are dropped and a leading
+// / is treated as the root of the archive, entries escaping it are rejected.
+// Names with a drive letter are rejected on every platform so that archives
+// extract the same everywhere.
+func archiveEntryPath(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || hasDriveLetter(cleaned) {
+		return "", fmt.Errorf("Archive entry %s is outside of the extraction directory", name)
+	}
+
+	cleaned = strings.TrimLeft(cleaned, "/")
+	if cleaned == "" {
+		cleaned = "."
+	}
+
+	entryPath := filepath.FromSlash(cleaned)
+	if filepath.VolumeName(entryPath) != "" || filepath.IsAbs(entryPath) {
+		return "", fmt.Errorf("Archive entry %s is outside of the extraction directory", name)
+	}
+
+	return entryPath, nil
+}
+
+func hasDriveLetter(name string) bool {
+	if len(name) < 2 || name[1] != ':' {
+		return false
+	}
+
+	letter := name[0] | 0x20
+	return letter >= 'a' && letter <= 'z'
+}
+
+func writeArchiveEntry(destination string, name string, mode os.FileMode, reader io.Reader) error {
+	entryPath, err := archiveEntryPath(name)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(destination, entryPath)
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	if mode.Perm() == 0 {
+		mode = 0644
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func makeArchiveDir(destination string, name string) error {
+	entryPath, err := archiveEntryPath(name)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(filepath.Join(destination, entryPath), 0755)
+}
+
+func (updater *Updater) extractZipTo(src string, destination string) error {
+	uncompressedStream, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("ExtractZip: NewReader failed %w", err)
+	}
+	defer uncompressedStream.Close()
+
+	progress := updater.zipProgress(uncompressedStream.File)
+	limit := updater.newExtractLimit()
+	for _, f := range uncompressedStream.File {
+		mode := f.FileInfo().Mode()
+		if mode.IsDir() {
+			err = makeArchiveDir(destination, f.Name)
+			if err != nil {
+				return fmt.Errorf("ExtractZip: %w", err)
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("ExtractZip: failed to open file %w", err)
+		}
+
+		reader, err := limit.wrap(f.Name, int64(f.UncompressedSize64), progress.wrap(rc))
+		if err == nil {
+			err = writeArchiveEntry(destination, f.Name, mode, reader)
+		}
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("ExtractZip: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (updater *Updater) extractTarballTo(name string, src string, destination string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	uncompressedStream, err := decompressTarball(name, updater.fileProgress(src).wrap(file))
+	if err != nil {
+		return fmt.Errorf("ExtractTarGz: NewReader failed %w", err)
+	}
+	defer uncompressedStream.Close()
+
+	tarReader := tar.NewReader(uncompressedStream)
+	limit := updater.newExtractLimit()
+
+	for {
+		header, err := tarReader.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("ExtractTarGz: Next() failed: %w", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = makeArchiveDir(destination, header.Name)
+		case tar.TypeReg:
+			var reader io.Reader
+			reader, err = limit.wrap(header.Name, header.Size, tarReader)
+			if err == nil {
+				err = writeArchiveEntry(destination, header.Name, header.FileInfo().Mode(), reader)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ExtractTarGz: %w", err)
+		}
+	}
+}
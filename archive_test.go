@@ -0,0 +1,49 @@
+package updater
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveEntryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "app", want: "app"},
+		{name: "./app", want: "app"},
+		{name: "/app", want: "app"},
+		{name: "bin/app", want: filepath.Join("bin", "app")},
+		{name: "bin\\app", want: filepath.Join("bin", "app")},
+		{name: "a/../app", want: "app"},
+		{name: "./", want: "."},
+		{name: "", want: "."},
+		{name: "/../x", want: "x"},
+		{name: "..", wantErr: true},
+		{name: "../x", wantErr: true},
+		{name: "a/../../x", wantErr: true},
+		{name: "..\\x", wantErr: true},
+		{name: "C:\\x", wantErr: true},
+		{name: "c:/x", wantErr: true},
+		{name: "C:", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := archiveEntryPath(test.name)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("archiveEntryPath(%q) = %q, want an error", test.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("archiveEntryPath(%q) error = %v", test.name, err)
+			}
+			if got != test.want {
+				t.Errorf("archiveEntryPath(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	azureStorageResource = "https://storage.azure.com/"
+	azureStorageVersion  = "2020-04-08"
+)
+
+// AzureBlobSource reads the manifest and assets from an Azure Blob Storage
+// container, authenticated with a SAS token or a managed identity.
+type AzureBlobSource struct {
+	Account   string
+	Container string
+	// Prefix is prepended to the manifest and asset names, e.g., "releases/".
+	Prefix string
+	// Manifest is the name of the manifest blob, relative to Prefix.
+	Manifest string
+	// SasToken is a shared access signature granting read access to the
+	// container. Without it, requests are authenticated with the managed
+	// identity of the host.
+	SasToken string
+	// ClientId selects a user assigned managed identity.
+	ClientId string
+	// Endpoint defaults to https://<Account>.blob.core.windows.net.
+	Endpoint string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	tokenMu sync.Mutex
+	token   *oauthToken
+}
+
+type azureTokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+	ExpiresOn   json.Number `json:"expires_on"`
+}
+
+func (source *AzureBlobSource) client() *http.Client {
+	if source.Client != nil {
+		return source.Client
+	}
+
+	return http.DefaultClient
+}
+
+func (source *AzureBlobSource) FetchManifest(ctx context.Context) (io.ReadCloser, int64, error) {
+	return source.FetchAsset(ctx, source.Manifest)
+}
+
+func (source *AzureBlobSource) FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	blob := strings.TrimPrefix(source.Prefix+name, "/")
+
+	endpoint := fmt.Sprintf("https://%s.blob.core.windows.net", source.Account)
+	if source.Endpoint != "" {
+		endpoint = strings.TrimSuffix(source.Endpoint, "/")
+	}
+	blobUrl := endpoint + "/" + url.PathEscape(source.Container) + "/" + escapeObjectPath(blob)
+	if source.SasToken != "" {
+		blobUrl += "?" + strings.TrimPrefix(source.SasToken, "?")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", blobUrl, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.Header.Set("x-ms-version", azureStorageVersion)
+
+	if source.SasToken == "" {
+		token, err := source.accessToken(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("Error downloading blob %s/%s. %w", source.Container, blob, httpStatusError(resp))
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+func (source *AzureBlobSource) accessToken(ctx context.Context) (string, error) {
+	source.tokenMu.Lock()
+	defer source.tokenMu.Unlock()
+
+	if source.token.valid() {
+		return source.token.accessToken, nil
+	}
+
+	token, err := source.managedIdentityToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get a managed identity token. %w", err)
+	}
+	source.token = token
+
+	return token.accessToken, nil
+}
+
+// managedIdentityToken requests a token for Azure Storage from the App
+// Service identity endpoint, when available, or the instance metadata
+// service.
+func (source *AzureBlobSource) managedIdentityToken(ctx context.Context) (*oauthToken, error) {
+	query := url.Values{"resource": {azureStorageResource}}
+	if source.ClientId != "" {
+		query.Set("client_id", source.ClientId)
+	}
+
+	var request *http.Request
+	var err error
+	if endpoint := os.Getenv("IDENTITY_ENDPOINT"); endpoint != "" && os.Getenv("IDENTITY_HEADER") != "" {
+		query.Set("api-version", "2019-08-01")
+		request, err = http.NewRequestWithContext(ctx, "GET", endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("X-IDENTITY-HEADER", os.Getenv("IDENTITY_HEADER"))
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		query.Set("api-version", "2018-02-01")
+		request, err = http.NewRequestWithContext(ctx, "GET", "http://169.254.169.254/metadata/identity/oauth2/token?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Metadata", "true")
+	}
+
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Error requesting an access token from %s. %w", request.URL.Host, httpStatusError(resp))
+	}
+
+	var token azureTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid access token response. %w", err)
+	}
+
+	expiry := time.Now()
+	if expiresIn, err := token.ExpiresIn.Int64(); err == nil {
+		expiry = expiry.Add(time.Duration(expiresIn) * time.Second)
+	} else if expiresOn, err := token.ExpiresOn.Int64(); err == nil {
+		expiry = time.Unix(expiresOn, 0)
+	}
+
+	return &oauthToken{accessToken: token.AccessToken, expiry: expiry}, nil
+}
@@ -0,0 +1,57 @@
+package updater
+
+import (
+	"context"
+	"time"
+)
+
+// maxBackgroundFailures caps the backoff of failing background checks at
+// 2^maxBackgroundFailures times the interval.
+const maxBackgroundFailures = 4
+
+// StartBackgroundChecks checks for updates every interval until ctx is done,
+// calling callback from a background goroutine when an update becomes
+// available. The callback is called once per version. Failed checks are not
+// reported, the interval doubles after each consecutive failure, up to 16
+// times the interval, and resets after a successful check.
+func (updater *Updater) StartBackgroundChecks(ctx context.Context, interval time.Duration, callback func(info *UpdateInfo)) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go updater.backgroundChecks(ctx, interval, callback)
+}
+
+func (updater *Updater) backgroundChecks(ctx context.Context, interval time.Duration, callback func(info *UpdateInfo)) {
+	reported := ""
+	failures := 0
+	for {
+		info, err := updater.CheckForAvailableUpdateInfoContext(ctx)
+		delay := interval
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			if failures < maxBackgroundFailures {
+				failures++
+			}
+			if interval<<failures > interval {
+				delay = interval << failures
+			}
+		default:
+			failures = 0
+			if info != nil && info.Version != reported {
+				reported = info.Version
+				callback(info)
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
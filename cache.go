@@ -0,0 +1,176 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// The artifact cache keeps verified downloads in CacheDir, named by their
+// sha256 checksum, so an update interrupted after the download resumes
+// without downloading again. index.json maps "<version>/<name>" to the
+// checksum of the verified artifact.
+const cacheIndexFile = "index.json"
+
+func cacheKey(info *downloadInfo, name string) string {
+	return info.manifest.Version + "/" + name
+}
+
+func (updater *Updater) readCacheIndex() (map[string]string, error) {
+	index := make(map[string]string)
+
+	data, err := os.ReadFile(filepath.Join(updater.config.CacheDir, cacheIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &index)
+	if err != nil {
+		// A corrupt index only costs a download.
+		return make(map[string]string), nil
+	}
+
+	return index, nil
+}
+
+func (updater *Updater) writeCacheIndex(index map[string]string) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	indexPath := filepath.Join(updater.config.CacheDir, cacheIndexFile)
+	tempPath := indexPath + "." + uuid.NewString()
+	err = os.WriteFile(tempPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tempPath, indexPath)
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
+
+func copyFile(src string, destination string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		os.Remove(destination)
+		return err
+	}
+
+	err = out.Close()
+	if err != nil {
+		os.Remove(destination)
+	}
+	return err
+}
+
+// cachedDownload returns a temporary copy of the cached artifact, or the empty
+// string if the artifact is not cached or no longer verifies.
+func (updater *Updater) cachedDownload(ctx context.Context, info *downloadInfo, name string) string {
+	index, err := updater.readCacheIndex()
+	if err != nil {
+		return ""
+	}
+
+	checksum, ok := index[cacheKey(info, name)]
+	if !ok {
+		return ""
+	}
+
+	cachedPath := filepath.Join(updater.config.CacheDir, checksum)
+	actual, err := fileSha256(cachedPath)
+	if err != nil || actual != checksum {
+		os.Remove(cachedPath)
+		return ""
+	}
+
+	tempFile := filepath.Join(os.TempDir(), uuid.NewString())
+	err = copyFile(cachedPath, tempFile)
+	if err != nil {
+		return ""
+	}
+
+	updater.setState(StateVerifying)
+	err = updater.verifyDownload(ctx, info, name, tempFile)
+	if err != nil {
+		os.Remove(tempFile)
+		return ""
+	}
+
+	return tempFile
+}
+
+// cacheDownload stores a verified artifact in the cache. Caching is best
+// effort, failures only mean the artifact is downloaded again.
+func (updater *Updater) cacheDownload(info *downloadInfo, name string, path string) {
+	checksum, err := fileSha256(path)
+	if err != nil {
+		return
+	}
+
+	_ = updater.cacheArtifact(cacheKey(info, name), checksum, path)
+}
+
+// cacheArtifact copies the artifact at path, whose checksum is checksum, into
+// the cache and adds it to the index under key.
+func (updater *Updater) cacheArtifact(key string, checksum string, path string) error {
+	err := os.MkdirAll(updater.config.CacheDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	cachedPath := filepath.Join(updater.config.CacheDir, checksum)
+	tempPath := cachedPath + "." + uuid.NewString()
+	err = copyFile(path, tempPath)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempPath, cachedPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	index, err := updater.readCacheIndex()
+	if err != nil {
+		return err
+	}
+	index[key] = checksum
+	return updater.writeCacheIndex(index)
+}
+
+// clearCache removes the cached artifacts once an update is installed.
+func (updater *Updater) clearCache() {
+	index, err := updater.readCacheIndex()
+	if err != nil {
+		return
+	}
+
+	for _, checksum := range index {
+		os.Remove(filepath.Join(updater.config.CacheDir, checksum))
+	}
+	os.Remove(filepath.Join(updater.config.CacheDir, cacheIndexFile))
+}
@@ -0,0 +1,74 @@
+package updater
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Channel returns the release channel the updater follows, the empty string
+// for the default channel.
+func (updater *Updater) Channel() string {
+	updater.stateMu.Lock()
+	defer updater.stateMu.Unlock()
+
+	return updater.channel
+}
+
+// SetChannel switches the release channel, e.g., when the user opts into
+// beta releases. It takes effect on the next manifest fetch.
+func (updater *Updater) SetChannel(channel string) {
+	updater.stateMu.Lock()
+	defer updater.stateMu.Unlock()
+
+	updater.channel = channel
+}
+
+// channelManifest returns the manifest of the channel. The top level of the
+// manifest is the default channel, fields set on a channel override it. A
+// channel with its own version does not inherit the fields describing the
+// release of the default channel, its checksums, sizes, signatures and
+// patches in particular.
+func (manifest *UpdaterManifest) channelManifest(channel string) (*UpdaterManifest, error) {
+	if channel == "" {
+		return manifest, nil
+	}
+
+	override := manifest.Channels[channel]
+	if override == nil {
+		return nil, fmt.Errorf("Manifest has no channel %s", channel)
+	}
+
+	base := *manifest
+	version := strings.TrimSpace(override.Version)
+	if version != "" && version != strings.TrimSpace(manifest.Version) {
+		base.Checksums = nil
+		base.Sizes = nil
+		base.Jws = nil
+		base.Patches = nil
+		base.BuildTime = time.Time{}
+		base.Rollout = nil
+		base.ReleaseNotes = ""
+		base.PublishedAt = time.Time{}
+		base.Url = ""
+	}
+
+	return base.withOverride(override), nil
+}
+
+// withOverride returns a copy of the manifest with the fields set in override
+// replaced. Channels are never overridden.
+func (manifest *UpdaterManifest) withOverride(override *UpdaterManifest) *UpdaterManifest {
+	resolved := *manifest
+	src := reflect.ValueOf(override).Elem()
+	dst := reflect.ValueOf(&resolved).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if dst.Field(i).CanSet() && !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	resolved.Channels = manifest.Channels
+
+	return &resolved
+}
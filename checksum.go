@@ -0,0 +1,124 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrChecksumNotAllowed = errors.New("Checksum is not in the allowed checksums")
+	ErrChecksumMismatch   = errors.New("Checksum mismatch")
+)
+
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// parseChecksums parses the output of sha256sum, mapping file names to their
+// checksums. Both the text and binary (*name) formats are supported.
+func parseChecksums(data []byte) map[string]string {
+	checksums := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "*")
+		checksums[name] = strings.ToLower(fields[0])
+	}
+
+	return checksums
+}
+
+func lookupChecksum(checksums map[string]string, name string) (string, bool) {
+	if checksum, ok := checksums[name]; ok {
+		return checksum, true
+	}
+
+	for entry, checksum := range checksums {
+		if filepath.Base(entry) == name {
+			return checksum, true
+		}
+	}
+
+	return "", false
+}
+
+func verifyArchiveChecksum(ex *extraction, checksumFile string, binaryName string) error {
+	if ex.checksums == nil {
+		return fmt.Errorf("Archive does not contain the checksum file %s", checksumFile)
+	}
+
+	expected, ok := lookupChecksum(parseChecksums(ex.checksums), binaryName)
+	if !ok {
+		return fmt.Errorf("Checksum file %s does not list %s", checksumFile, binaryName)
+	}
+
+	actual, err := fileSha256(ex.binaryPath)
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return fmt.Errorf("%w for %s. Expected %s but got %s", ErrChecksumMismatch, binaryName, expected, actual)
+	}
+
+	return nil
+}
+
+func verifyAllowedChecksum(name string, path string, allowed []string) error {
+	actual, err := fileSha256(path)
+	if err != nil {
+		return err
+	}
+
+	for _, checksum := range allowed {
+		if strings.EqualFold(strings.TrimSpace(checksum), actual) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w. %s has checksum %s", ErrChecksumNotAllowed, name, actual)
+}
+
+// verifyManifestChecksum verifies a download against the checksums listed in
+// the manifest. Once the manifest lists checksums, every artifact must be
+// listed.
+func verifyManifestChecksum(manifest *UpdaterManifest, name string, path string) error {
+	expected, ok := manifest.Checksums[name]
+	if !ok {
+		return fmt.Errorf("%w. Manifest does not list a checksum for %s", ErrChecksumMismatch, name)
+	}
+
+	actual, err := fileSha256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimSpace(expected), actual) {
+		return fmt.Errorf("%w for %s. Expected %s but got %s", ErrChecksumMismatch, name, expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type WindowsCleanupStrategy string
+
+const (
+	CleanupOnNextStart WindowsCleanupStrategy = "onNextStart"
+	CleanupOnReboot    WindowsCleanupStrategy = "onReboot"
+	CleanupLeave       WindowsCleanupStrategy = "leave"
+)
+
+func oldBinaryPaths(binaryPath string) ([]string, error) {
+	paths, err := filepath.Glob(globEscape(binaryPath) + ".*.old")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{binaryPath + ".old"}, paths...), nil
+}
+
+func globEscape(path string) string {
+	escaped := make([]rune, 0, len(path))
+	for _, char := range path {
+		switch char {
+		case '*', '?', '[', ']':
+			escaped = append(escaped, '[', char, ']')
+		default:
+			escaped = append(escaped, char)
+		}
+	}
+
+	return string(escaped)
+}
+
+// Cleanup removes binaries left behind by previous updates on Windows, where
+// the running executable cannot be deleted while it is running. Applications
+// using CleanupOnNextStart should call it on startup. Binaries that are still
+// running are scheduled for deletion on the next reboot when possible. The
+// backup kept for Rollback is not removed. Cleanup also completes the swap of
+// a binary interrupted by a crash.
+func (updater *Updater) Cleanup() error {
+	binaryPath, err := updater.targetPath("")
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(updater.recoverSwap(binaryPath), updater.cleanupOldBinaries(binaryPath))
+}
+
+func (updater *Updater) cleanupOldBinaries(binaryPath string) error {
+	paths, err := oldBinaryPaths(binaryPath)
+	if err != nil {
+		return err
+	}
+
+	kept := updater.rollbackBackup()
+	var errs []error
+	for _, path := range paths {
+		if path == kept {
+			continue
+		}
+
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) && removeOnReboot(path) != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package updater
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+func (updater *Updater) backupPath(binaryPath string) string {
+	return filepath.Join(os.TempDir(), uuid.NewString())
+}
+
+// cleanupBackup removes the backup right away, the running executable keeps
+// its file open regardless.
+func (updater *Updater) cleanupBackup(backupPath string) error {
+	return os.Remove(backupPath)
+}
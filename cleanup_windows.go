@@ -0,0 +1,30 @@
+package updater
+
+import (
+	"errors"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// backupPath keeps the previous binary next to the target. A running
+// executable can be renamed but not deleted on Windows and renames across
+// volumes fail.
+func (updater *Updater) backupPath(binaryPath string) string {
+	oldPath := binaryPath + ".old"
+	err := os.Remove(oldPath)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return oldPath
+	}
+
+	return binaryPath + "." + uuid.NewString() + ".old"
+}
+
+func (updater *Updater) cleanupBackup(backupPath string) error {
+	switch updater.config.WindowsCleanupStrategy {
+	case CleanupOnReboot:
+		return removeOnReboot(backupPath)
+	default:
+		return nil
+	}
+}
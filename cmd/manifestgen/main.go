@@ -0,0 +1,52 @@
+// Command manifestgen generates the updater manifest of a GoReleaser release.
+//
+//	goreleaser release --clean
+//	manifestgen -dist dist -o dist/manifest.json
+//
+// The manifest is written to stdout unless -o is set. See package
+// github.com/dworthen/updater/manifestgen.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dworthen/updater/manifestgen"
+)
+
+func main() {
+	var options manifestgen.Options
+	dist := flag.String("dist", "dist", "GoReleaser dist directory or artifacts.json")
+	output := flag.String("o", "", "File to write the manifest to, defaults to stdout")
+	flag.StringVar(&options.Version, "version", "", "Version of the release, defaults to the version in metadata.json")
+	flag.StringVar(&options.ID, "id", "", "GoReleaser archives or builds id to publish, when the release has several")
+	flag.StringVar(&options.Binary, "binary", "", "Name of the binary within archives, defaults to the archived binary")
+	flag.StringVar(&options.Product, "product", "", "Product name written to the manifest")
+	flag.Parse()
+
+	err := run(*dist, *output, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dist string, output string, options manifestgen.Options) error {
+	manifest, err := manifestgen.Generate(dist, options)
+	if err != nil {
+		return err
+	}
+
+	data, err := manifest.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(output, data, 0644)
+}
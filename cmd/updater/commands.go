@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dworthen/updater"
+	"github.com/dworthen/updater/manifestgen"
+)
+
+func runCheck(args []string, stdout io.Writer, stderr io.Writer) error {
+	var opts options
+	flags := newFlagSet("check", "Checks for an update of the target binary.", stderr)
+	opts.register(flags)
+	asJson := flags.Bool("json", false, "Print the result as JSON")
+	exitCode := flags.Bool("exit-code", false, fmt.Sprintf("Exit with code %d when an update is available", exitUpdateAvailable))
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := opts.context()
+	defer cancel()
+	config, err := opts.config(ctx, stderr, true)
+	if err != nil {
+		return err
+	}
+
+	info, err := updater.New(config).CheckForAvailableUpdateInfoContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		result := struct {
+			Available      bool       `json:"available"`
+			CurrentVersion string     `json:"currentVersion"`
+			Version        string     `json:"version,omitempty"`
+			ReleaseNotes   string     `json:"releaseNotes,omitempty"`
+			PublishedAt    *time.Time `json:"publishedAt,omitempty"`
+			Url            string     `json:"url,omitempty"`
+		}{
+			Available:      info != nil,
+			CurrentVersion: config.CurrentVersion,
+		}
+		if info != nil {
+			result.Version = info.Version
+			result.ReleaseNotes = info.ReleaseNotes
+			result.PublishedAt = optionalTime(info.PublishedAt)
+			result.Url = info.Url
+		}
+		err = writeJson(stdout, result)
+		if err != nil {
+			return err
+		}
+	} else if info == nil {
+		fmt.Fprintf(stdout, "No update available, %s is the latest version\n", config.CurrentVersion)
+	} else {
+		fmt.Fprintf(stdout, "Update available: %s -> %s\n", config.CurrentVersion, info.Version)
+	}
+
+	if info != nil && *exitCode {
+		return errUpdateAvailable
+	}
+
+	return nil
+}
+
+func runUpdate(args []string, stdout io.Writer, stderr io.Writer) error {
+	var opts options
+	flags := newFlagSet("update", "Installs the latest version, or -version, of the target binary.", stderr)
+	opts.register(flags)
+	version := flags.String("version", env("UPDATER_VERSION", ""), "Version to install instead of the latest")
+	allowDowngrade := flags.Bool("allow-downgrade", envBool("UPDATER_ALLOW_DOWNGRADE", false), "Allow -version to be older than the current version")
+	quiet := flags.Bool("quiet", false, "Only print errors")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if opts.target == "" {
+		return fmt.Errorf("%w. Set -target, the binary to update", errUsage)
+	}
+
+	ctx, cancel := opts.context()
+	defer cancel()
+	config, err := opts.config(ctx, stderr, true)
+	if err != nil {
+		return err
+	}
+	if !*quiet {
+		config.OnEvent = newProgressPrinter(stderr).print
+	}
+
+	pkgUpdater := updater.New(config)
+	// Reports are sent for at most 30 seconds, regardless of -timeout.
+	defer pkgUpdater.WaitReports(context.Background())
+	if *version == "" {
+		err = pkgUpdater.UpdateContext(ctx)
+	} else {
+		err = pkgUpdater.UpdateToContext(ctx, *version)
+		if errors.Is(err, updater.ErrDowngrade) && *allowDowngrade {
+			err = pkgUpdater.DowngradeContext(ctx, *version)
+		}
+	}
+	if errors.Is(err, updater.ErrNoUpdateAvailable) {
+		if !*quiet {
+			fmt.Fprintf(stdout, "No update available, %s is the latest version\n", config.CurrentVersion)
+		}
+		return nil
+	}
+
+	return err
+}
+
+func runRollback(args []string, stdout io.Writer, stderr io.Writer) error {
+	var opts options
+	flags := newFlagSet("rollback", "Restores the version replaced by the last update, recorded in -rollback-path.", stderr)
+	opts.register(flags)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if opts.rollbackPath == "" {
+		return fmt.Errorf("%w. Set -rollback-path, the file recording the last update", errUsage)
+	}
+
+	ctx, cancel := opts.context()
+	defer cancel()
+	config, err := opts.config(ctx, stderr, false)
+	if err != nil {
+		return err
+	}
+
+	err = updater.New(config).Rollback()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "Rolled back to the previous version")
+	return nil
+}
+
+func runVersions(args []string, stdout io.Writer, stderr io.Writer) error {
+	var opts options
+	flags := newFlagSet("versions", "Lists the versions published in the manifest, across all channels, newest first.", stderr)
+	opts.register(flags)
+	asJson := flags.Bool("json", false, "Print the versions as JSON")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := opts.context()
+	defer cancel()
+	config, err := opts.config(ctx, stderr, false)
+	if err != nil {
+		return err
+	}
+
+	versions, err := updater.New(config).ListAvailableVersionsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		type version struct {
+			Version     string     `json:"version"`
+			Channel     string     `json:"channel,omitempty"`
+			PublishedAt *time.Time `json:"publishedAt,omitempty"`
+		}
+		result := make([]version, 0, len(versions))
+		for _, available := range versions {
+			result = append(result, version{available.Version, available.Channel, optionalTime(available.PublishedAt)})
+		}
+		return writeJson(stdout, result)
+	}
+
+	table := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "VERSION\tCHANNEL\tPUBLISHED")
+	for _, available := range versions {
+		channel := available.Channel
+		if channel == "" {
+			channel = "-"
+		}
+		published := "-"
+		if !available.PublishedAt.IsZero() {
+			published = available.PublishedAt.Format(time.DateOnly)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", available.Version, channel, published)
+	}
+	return table.Flush()
+}
+
+func runManifest(args []string, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "generate" {
+		return fmt.Errorf("%w. Usage: updater manifest generate [flags]", errUsage)
+	}
+
+	var options manifestgen.Options
+	flags := newFlagSet("manifest generate", "Generates the manifest of a GoReleaser release.", stderr)
+	dist := flags.String("dist", "dist", "GoReleaser dist directory or artifacts.json")
+	output := flags.String("o", "", "File to write the manifest to, defaults to stdout")
+	flags.StringVar(&options.Version, "version", "", "Version of the release, defaults to the version in metadata.json")
+	flags.StringVar(&options.ID, "id", "", "GoReleaser archives or builds id to publish, when the release has several")
+	flags.StringVar(&options.Binary, "binary", "", "Name of the binary within archives, defaults to the archived binary")
+	flags.StringVar(&options.Product, "product", "", "Product name written to the manifest")
+	err := parseFlags(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	manifest, err := manifestgen.Generate(*dist, options)
+	if err != nil {
+		return err
+	}
+
+	data, err := manifest.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(*output, data, 0644)
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	if err != nil {
+		// The flag set printed the error and the usage.
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w. Unexpected argument %q", errUsage, flags.Arg(0))
+	}
+
+	return nil
+}
+
+// optionalTime leaves unknown times out of JSON output.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func writeJson(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// progressPrinter prints the progress of an update, redrawing the download
+// progress in place on terminals.
+type progressPrinter struct {
+	w        io.Writer
+	terminal bool
+	percent  int64
+}
+
+func newProgressPrinter(w io.Writer) *progressPrinter {
+	printer := &progressPrinter{w: w, percent: -1}
+	if file, ok := w.(*os.File); ok {
+		info, err := file.Stat()
+		printer.terminal = err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return printer
+}
+
+func (printer *progressPrinter) print(event updater.Event) {
+	switch event.Type {
+	case updater.EventDownloadStarted:
+		printer.percent = -1
+		fmt.Fprintf(printer.w, "Downloading %s\n", event.Name)
+	case updater.EventDownloadProgress:
+		if !printer.terminal || event.TotalBytes <= 0 {
+			return
+		}
+		percent := event.BytesDownloaded * 100 / event.TotalBytes
+		if percent == printer.percent {
+			return
+		}
+		printer.percent = percent
+		fmt.Fprintf(printer.w, "\r%3d%% %s", percent, formatBytes(event.BytesDownloaded))
+		if percent == 100 {
+			fmt.Fprintln(printer.w)
+		}
+	case updater.EventVerified:
+		fmt.Fprintf(printer.w, "Verified %s\n", event.Version)
+	case updater.EventApplied:
+		fmt.Fprintf(printer.w, "Updated to %s\n", event.Version)
+	case updater.EventRolledBack:
+		fmt.Fprintf(printer.w, "Rolled back to the previous version\n")
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	suffix := "KMGT"
+	i := -1
+	for value >= unit && i < len(suffix)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, suffix[i])
+}
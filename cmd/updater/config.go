@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dworthen/updater"
+)
+
+// errUsage is returned for invalid flags, exiting with code 2.
+var errUsage = errors.New("Invalid usage")
+
+// options are the flags shared by the commands using an updater.
+type options struct {
+	baseUrl        string
+	manifest       string
+	github         string
+	githubToken    string
+	target         string
+	currentVersion string
+	channel        string
+	rollbackPath   string
+	cacheDir       string
+	signingKeys    string
+	minisignKey    string
+	maxRetries     int
+	timeout        time.Duration
+	verbose        bool
+}
+
+func newFlagSet(name string, usage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: updater %s [flags]\n\n%s\n\nFlags:\n", name, usage)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+func (opts *options) register(flags *flag.FlagSet) {
+	flags.StringVar(&opts.baseUrl, "base-url", env("UPDATER_BASE_URL", ""), "Url where the manifest and archives/binaries are hosted")
+	flags.StringVar(&opts.manifest, "manifest", env("UPDATER_MANIFEST", "updater.config.json"), "Name of the manifest hosted at the base url")
+	flags.StringVar(&opts.github, "github", env("UPDATER_GITHUB", ""), "GitHub repository, owner/repo, publishing the releases instead of a base url")
+	flags.StringVar(&opts.githubToken, "github-token", env("UPDATER_GITHUB_TOKEN", os.Getenv("GITHUB_TOKEN")), "GitHub token for private repositories or higher rate limits, defaults to GITHUB_TOKEN")
+	flags.StringVar(&opts.target, "target", env("UPDATER_TARGET", ""), "Path of the binary to update")
+	flags.StringVar(&opts.currentVersion, "current-version", env("UPDATER_CURRENT_VERSION", ""), "Installed version, defaults to the version printed by the target with --version")
+	flags.StringVar(&opts.channel, "channel", env("UPDATER_CHANNEL", ""), "Release channel")
+	flags.StringVar(&opts.rollbackPath, "rollback-path", env("UPDATER_ROLLBACK_PATH", ""), "File recording the backup of the replaced version, enables rollback")
+	flags.StringVar(&opts.cacheDir, "cache-dir", env("UPDATER_CACHE_DIR", ""), "Directory caching downloads")
+	flags.StringVar(&opts.signingKeys, "signing-keys", env("UPDATER_SIGNING_KEYS", ""), "Comma separated base64 ed25519 keys the manifest must be signed with")
+	flags.StringVar(&opts.minisignKey, "minisign-key", env("UPDATER_MINISIGN_KEY", ""), "Minisign public key the manifest must be signed with")
+	flags.IntVar(&opts.maxRetries, "retries", envInt("UPDATER_RETRIES", 2), "Number of times failed requests are retried")
+	flags.DurationVar(&opts.timeout, "timeout", envDuration("UPDATER_TIMEOUT", 0), "Time limit of the command, e.g., 5m, no limit by default")
+	flags.BoolVar(&opts.verbose, "verbose", envBool("UPDATER_VERBOSE", false), "Log each step to stderr")
+}
+
+func (opts *options) context() (context.Context, context.CancelFunc) {
+	if opts.timeout > 0 {
+		return context.WithTimeout(context.Background(), opts.timeout)
+	}
+
+	return context.WithCancel(context.Background())
+}
+
+// config returns the updater config of the flags. The current version is
+// only required by commands comparing versions.
+func (opts *options) config(ctx context.Context, stderr io.Writer, requireVersion bool) (*updater.UpdaterConfig, error) {
+	config := &updater.UpdaterConfig{
+		BaseUrl:           opts.baseUrl,
+		UpdaterConfig:     opts.manifest,
+		TargetPath:        opts.target,
+		CurrentVersion:    opts.currentVersion,
+		Channel:           opts.channel,
+		RollbackPath:      opts.rollbackPath,
+		CacheDir:          opts.cacheDir,
+		MinisignPublicKey: opts.minisignKey,
+		MaxRetries:        opts.maxRetries,
+	}
+
+	switch {
+	case opts.github != "":
+		owner, repo, ok := strings.Cut(opts.github, "/")
+		if !ok || owner == "" || repo == "" {
+			return nil, fmt.Errorf("%w. -github must be owner/repo but got %q", errUsage, opts.github)
+		}
+		config.GitHubSource = &updater.GitHubSource{Owner: owner, Repo: repo, Token: opts.githubToken}
+	case opts.baseUrl == "":
+		return nil, fmt.Errorf("%w. Set -base-url or -github", errUsage)
+	}
+
+	for _, key := range strings.Split(opts.signingKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			config.SigningKeys = append(config.SigningKeys, key)
+		}
+	}
+
+	if opts.verbose {
+		config.Logger = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	if requireVersion && config.CurrentVersion == "" {
+		if opts.target == "" {
+			return nil, fmt.Errorf("%w. Set -target or -current-version", errUsage)
+		}
+		version, err := detectVersion(ctx, opts.target)
+		if err != nil {
+			return nil, err
+		}
+		config.CurrentVersion = version
+	}
+
+	return config, nil
+}
+
+var versionPattern = regexp.MustCompile(`v?[0-9]+\.[0-9]+(\.[0-9]+)?(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?`)
+
+// detectVersion runs the target with --version and returns the first version
+// in its output.
+func detectVersion(ctx context.Context, target string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, target, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("Cannot tell the version of %s, set -current-version. %w", target, err)
+	}
+
+	version := versionPattern.FindString(string(output))
+	if version == "" {
+		return "", fmt.Errorf("Cannot tell the version of %s, set -current-version. %s --version printed no version", target, target)
+	}
+
+	return version, nil
+}
+
+func env(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+
+	return fallback
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func envBool(name string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,87 @@
+// Command updater checks for and installs updates of any binary published
+// with an updater manifest or on GitHub Releases, for scripts and CI.
+//
+//	updater check -base-url https://example.com/myapp -target /usr/local/bin/myapp
+//	updater update -base-url https://example.com/myapp -target /usr/local/bin/myapp
+//	updater rollback -target /usr/local/bin/myapp -rollback-path /var/lib/myapp/rollback.json
+//	updater versions -github owner/repo
+//	updater manifest generate -dist dist -o dist/manifest.json
+//
+// Flags default to UPDATER_* environment variables, e.g., UPDATER_BASE_URL
+// for -base-url. Run updater <command> -h for the flags of a command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: updater <command> [flags]
+
+Commands:
+  check              Check for an update of the target binary
+  update             Install the latest version, or -version, of the target binary
+  rollback           Restore the version replaced by the last update
+  versions           List the versions published in the manifest
+  manifest generate  Generate a manifest from a GoReleaser dist directory
+
+Flags default to UPDATER_* environment variables, e.g., UPDATER_BASE_URL for
+-base-url. Run updater <command> -h for the flags of a command.
+`
+
+// exitUpdateAvailable is the exit code of check -exit-code when an update is
+// available.
+const exitUpdateAvailable = 10
+
+// errUpdateAvailable is returned by check -exit-code when an update is
+// available.
+var errUpdateAvailable = errors.New("Update available")
+
+var commands = map[string]func(args []string, stdout io.Writer, stderr io.Writer) error{
+	"check":    runCheck,
+	"update":   runUpdate,
+	"rollback": runRollback,
+	"versions": runVersions,
+	"manifest": runManifest,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	err := command(args[1:], stdout, stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUpdateAvailable):
+		return exitUpdateAvailable
+	case errors.Is(err, errUsage):
+		if err != errUsage {
+			fmt.Fprintln(stderr, err)
+		}
+		return 2
+	default:
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+}
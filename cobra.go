@@ -0,0 +1,196 @@
+package updater
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCobraCommand returns a self-update command for Cobra CLIs:
+//
+//	rootCmd.AddCommand(updater.NewCobraCommand(&updater.UpdaterConfig{...}))
+//
+// The command checks for an update, asks for confirmation unless --yes is
+// set and installs it, printing the download progress to stderr. Flags:
+// --check-only only reports whether an update is available, --channel
+// selects the release channel and --version installs a specific version.
+// The config is not modified, OnEvent is still called.
+func NewCobraCommand(config *UpdaterConfig) *cobra.Command {
+	var checkOnly, yes bool
+	var channel, version string
+
+	command := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update to the latest version",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			command.SilenceUsage = true
+			return runCobraCommand(command, config, checkOnly, yes, channel, version)
+		},
+	}
+
+	flags := command.Flags()
+	flags.BoolVar(&checkOnly, "check-only", false, "Only check whether an update is available")
+	flags.StringVar(&channel, "channel", config.Channel, "Release channel to update from")
+	flags.StringVar(&version, "version", "", "Version to install instead of the latest")
+	flags.BoolVarP(&yes, "yes", "y", false, "Update without asking for confirmation")
+
+	return command
+}
+
+func runCobraCommand(command *cobra.Command, config *UpdaterConfig, checkOnly bool, yes bool, channel string, version string) error {
+	ctx := command.Context()
+	stdout := command.OutOrStdout()
+	stderr := command.ErrOrStderr()
+	currentVersion := strings.TrimSpace(config.CurrentVersion)
+
+	commandConfig := *config
+	commandConfig.Channel = channel
+	onEvent := config.OnEvent
+	progress := newTerminalProgress(stderr)
+	commandConfig.OnEvent = func(event Event) {
+		progress.print(event)
+		if onEvent != nil {
+			onEvent(event)
+		}
+	}
+	updater := New(&commandConfig)
+	defer updater.WaitReports(context.Background())
+
+	if version == "" {
+		info, err := updater.CheckForAvailableUpdateInfoContext(ctx)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			fmt.Fprintf(stdout, "Already up to date (%s)\n", currentVersion)
+			return nil
+		}
+
+		version = info.Version
+		fmt.Fprintf(stdout, "Update available: %s -> %s\n", currentVersion, version)
+		if notes := strings.TrimSpace(info.ReleaseNotes); notes != "" {
+			fmt.Fprintf(stdout, "\n%s\n\n", notes)
+		}
+		if info.Url != "" {
+			fmt.Fprintf(stdout, "Release: %s\n", info.Url)
+		}
+	}
+	if checkOnly {
+		return nil
+	}
+
+	if !yes {
+		confirmed, err := confirm(command.InOrStdin(), stdout, fmt.Sprintf("Update to %s? [y/N] ", version))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Fprintln(stdout, "Update canceled")
+			return nil
+		}
+	}
+
+	err := updater.UpdateToContext(ctx, version)
+	if errors.Is(err, ErrNoUpdateAvailable) {
+		fmt.Fprintf(stdout, "Already up to date (%s)\n", currentVersion)
+		return nil
+	}
+	if err != nil && updater.State() == StateReadyToInstall {
+		fmt.Fprintf(stdout, "Downloaded %s, installing it was deferred. %s\n", version, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Updated %s -> %s\n", currentVersion, version)
+	return nil
+}
+
+// confirm asks a yes or no question, defaulting to no, e.g., when stdin is
+// closed.
+func confirm(stdin io.Reader, stdout io.Writer, question string) (bool, error) {
+	fmt.Fprint(stdout, question)
+	answer, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(stdout)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// terminalProgress prints the steps of an update, drawing a progress bar of
+// downloads on terminals.
+type terminalProgress struct {
+	w        io.Writer
+	terminal bool
+	drawn    int
+}
+
+func newTerminalProgress(w io.Writer) *terminalProgress {
+	progress := &terminalProgress{w: w, drawn: -1}
+	if file, ok := w.(*os.File); ok {
+		info, err := file.Stat()
+		progress.terminal = err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return progress
+}
+
+const progressBarWidth = 30
+
+func (progress *terminalProgress) print(event Event) {
+	switch event.Type {
+	case EventDownloadStarted:
+		progress.drawn = -1
+		fmt.Fprintf(progress.w, "Downloading %s\n", event.Name)
+	case EventDownloadProgress:
+		if !progress.terminal || event.TotalBytes <= 0 {
+			return
+		}
+		filled := int(min(event.BytesDownloaded*progressBarWidth/event.TotalBytes, progressBarWidth))
+		percent := int(min(event.BytesDownloaded*100/event.TotalBytes, 100))
+		if percent == progress.drawn {
+			return
+		}
+		progress.drawn = percent
+		fmt.Fprintf(progress.w, "\r[%s%s] %3d%% %s / %s", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), percent, formatBytes(event.BytesDownloaded), formatBytes(event.TotalBytes))
+		if percent == 100 {
+			fmt.Fprintln(progress.w)
+		}
+	case EventVerified:
+		fmt.Fprintln(progress.w, "Verified the download")
+	case EventRolledBack:
+		fmt.Fprintln(progress.w, "Installing the update failed, restored the previous version")
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	prefixes := "KMGT"
+	i := -1
+	for value >= unit && i < len(prefixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, prefixes[i])
+}
@@ -0,0 +1,140 @@
+package updater
+
+import (
+	"fmt"
+	"strings"
+)
+
+type versionComparator struct {
+	operator string
+	version  *semVersion
+}
+
+// versionConstraint is a set of alternatives separated by ||, each a space
+// separated list of comparators that must all match, e.g.,
+// ">=1.2.0 <1.2.5 || =1.3.0". Versions may be partial, e.g., "1.2" matches
+// any 1.2.x version, and ~ and ^ select patch and minor releases, e.g., "~1.2"
+// or "^1.2.3".
+type versionConstraint [][]versionComparator
+
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+func parseConstraint(constraint string) (versionConstraint, error) {
+	var parsed versionConstraint
+	for _, alternative := range strings.Split(constraint, "||") {
+		var comparators []versionComparator
+		for _, field := range strings.Fields(alternative) {
+			operator := "="
+			for _, candidate := range constraintOperators {
+				if strings.HasPrefix(field, candidate) {
+					operator = candidate
+					break
+				}
+			}
+
+			expanded, err := expandComparator(operator, strings.TrimPrefix(field, operator))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid version constraint %q. %w", constraint, err)
+			}
+			comparators = append(comparators, expanded...)
+		}
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("Invalid version constraint %q", constraint)
+		}
+		parsed = append(parsed, comparators)
+	}
+
+	return parsed, nil
+}
+
+// parsePartialVersion parses a version that may omit the minor and patch
+// numbers, returning the number of parts given.
+func parsePartialVersion(version string) (*semVersion, int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	core, _, _ := strings.Cut(trimmed, "-")
+	core, _, _ = strings.Cut(core, "+")
+	parts := strings.Count(core, ".") + 1
+	for i := parts; i < 3; i++ {
+		trimmed = strings.Replace(trimmed, core, core+".0", 1)
+		core += ".0"
+	}
+
+	parsed, err := parseVersion(trimmed)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return parsed, parts, nil
+}
+
+// expandComparator expands partial versions and the ~ and ^ operators into
+// plain comparators, e.g., "~1.2" into ">=1.2.0 <1.3.0".
+func expandComparator(operator string, version string) ([]versionComparator, error) {
+	lower, parts, err := parsePartialVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	// upper is the first version after the range selected by the operator.
+	upper := &semVersion{major: lower.major + 1}
+	switch {
+	case operator == "^" && lower.major == 0 && (lower.minor > 0 || parts == 2):
+		upper = &semVersion{minor: lower.minor + 1}
+	case operator == "^" && lower.major == 0 && parts == 3:
+		upper = &semVersion{minor: lower.minor, patch: lower.patch + 1}
+	case operator == "^":
+	case parts == 3 && operator != "~":
+		return []versionComparator{{operator: operator, version: lower}}, nil
+	case parts >= 2:
+		upper = &semVersion{major: lower.major, minor: lower.minor + 1}
+	}
+
+	// The lowest prerelease of upper, so that its prereleases are excluded
+	// from the range as well.
+	upper.prerelease = []string{"0"}
+
+	switch operator {
+	case "~", "^", "=":
+		return []versionComparator{{operator: ">=", version: lower}, {operator: "<", version: upper}}, nil
+	case "!=":
+		return nil, fmt.Errorf("Expected a full version after != but got %q", version)
+	case ">":
+		return []versionComparator{{operator: ">=", version: upper}}, nil
+	case "<=":
+		return []versionComparator{{operator: "<", version: upper}}, nil
+	default:
+		return []versionComparator{{operator: operator, version: lower}}, nil
+	}
+}
+
+func (comparator versionComparator) matches(version *semVersion) bool {
+	result := version.compare(comparator.version)
+	switch comparator.operator {
+	case ">=":
+		return result >= 0
+	case "<=":
+		return result <= 0
+	case "!=":
+		return result != 0
+	case ">":
+		return result > 0
+	case "<":
+		return result < 0
+	default:
+		return result == 0
+	}
+}
+
+func (constraint versionConstraint) matches(version *semVersion) bool {
+	for _, alternative := range constraint {
+		matched := true
+		for _, comparator := range alternative {
+			matched = matched && comparator.matches(version)
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
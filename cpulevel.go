@@ -0,0 +1,41 @@
+package updater
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/cpu"
+)
+
+// detectCPULevel reports the x86-64 microarchitecture level of the host, v2,
+// v3 or v4. It returns the empty string for the baseline level and on other
+// architectures.
+func detectCPULevel() string {
+	if runtime.GOARCH != "amd64" {
+		return ""
+	}
+
+	x86 := cpu.X86
+	if !(x86.HasCX16 && x86.HasPOPCNT && x86.HasSSE3 && x86.HasSSSE3 && x86.HasSSE41 && x86.HasSSE42) {
+		return ""
+	}
+	if !(x86.HasAVX && x86.HasAVX2 && x86.HasBMI1 && x86.HasBMI2 && x86.HasFMA && x86.HasOSXSAVE) {
+		return "v2"
+	}
+	if !(x86.HasAVX512F && x86.HasAVX512BW && x86.HasAVX512CD && x86.HasAVX512DQ && x86.HasAVX512VL) {
+		return "v3"
+	}
+
+	return "v4"
+}
+
+// cpuLevels returns the levels to try for level, from the highest down to v2.
+func cpuLevels(level string) []string {
+	levels := []string{"v4", "v3", "v2"}
+	for i, candidate := range levels {
+		if candidate == level {
+			return levels[i:]
+		}
+	}
+
+	return nil
+}
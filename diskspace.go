@@ -0,0 +1,51 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrInsufficientDiskSpace = errors.New("Insufficient disk space")
+
+// installParent returns the directory the update is installed into.
+func (updater *Updater) installParent(info *downloadInfo) (string, error) {
+	if updater.config.InstallDir != "" && info.archiveName != "" {
+		return filepath.Dir(filepath.Clean(updater.config.InstallDir)), nil
+	}
+
+	target, err := updater.targetPath(info.destName)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Dir(target), nil
+}
+
+// checkDiskSpace fails when the temp dir or the directory the update is
+// installed into has less than size bytes free. Directories whose free space
+// cannot be determined are not checked.
+func (updater *Updater) checkDiskSpace(info *downloadInfo, name string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+
+	dirs := []string{os.TempDir()}
+	installParent, err := updater.installParent(info)
+	if err == nil {
+		dirs = append(dirs, installParent)
+	}
+
+	for _, dir := range dirs {
+		free, err := freeDiskSpace(dir)
+		if err != nil {
+			continue
+		}
+		if free < size {
+			return fmt.Errorf("%w to download %s. %s has %d bytes free but %d bytes are needed", ErrInsufficientDiskSpace, name, dir, free, size)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package updater
+
+import "errors"
+
+func freeDiskSpace(dir string) (int64, error) {
+	return 0, errors.New("Free disk space is not supported on this platform")
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package updater
+
+import "golang.org/x/sys/unix"
+
+func freeDiskSpace(dir string) (int64, error) {
+	var stat unix.Statfs_t
+	err := unix.Statfs(dir, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
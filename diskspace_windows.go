@@ -0,0 +1,18 @@
+package updater
+
+import "golang.org/x/sys/windows"
+
+func freeDiskSpace(dir string) (int64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var free uint64
+	err = windows.GetDiskFreeSpaceEx(path, &free, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(free), nil
+}
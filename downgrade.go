@@ -0,0 +1,52 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrDowngrade = errors.New("Downgrade refused")
+
+// Downgrade installs a version older than the current version, e.g., to back
+// out of a release with a regression. ConfirmDowngrade, when set, is asked
+// first. With a RollbackPath, the current version is kept for Rollback like
+// with any other update.
+func (updater *Updater) Downgrade(version string) error {
+	return updater.DowngradeContext(context.Background(), version)
+}
+
+func (updater *Updater) DowngradeContext(ctx context.Context, version string) error {
+	return updater.runUpdate(ctx, strings.TrimSpace(version), true)
+}
+
+// checkDowngrade checks that version is older than the current version when
+// downgrading, and not older otherwise. Versions that are not semantic
+// versions cannot be ordered, so they cannot be downgraded to but are
+// installed by UpdateTo.
+func (updater *Updater) checkDowngrade(version string, downgrade bool) error {
+	currentVersion := strings.TrimSpace(updater.config.CurrentVersion)
+	current, currentErr := parseVersion(currentVersion)
+	target, err := parseVersion(version)
+	if currentErr != nil || err != nil {
+		if downgrade {
+			return fmt.Errorf("%w. Cannot order %q and %q, downgrades require semantic versions", ErrDowngrade, currentVersion, version)
+		}
+		return nil
+	}
+
+	older := target.compare(current) < 0
+	if older && !downgrade {
+		return fmt.Errorf("%w. %s is older than the current version %s, use Downgrade to install it", ErrDowngrade, version, currentVersion)
+	}
+	if !older && downgrade {
+		return fmt.Errorf("%w. %s is not older than the current version %s", ErrDowngrade, version, currentVersion)
+	}
+
+	if downgrade && updater.config.ConfirmDowngrade != nil && !updater.config.ConfirmDowngrade(currentVersion, version) {
+		return fmt.Errorf("%w. Downgrade from %s to %s was not confirmed", ErrDowngrade, currentVersion, version)
+	}
+
+	return nil
+}
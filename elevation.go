@@ -0,0 +1,68 @@
+package updater
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// RequiresElevation reports whether installing an update needs administrator
+// or root privileges because the install location is not writable by the
+// current user.
+func (updater *Updater) RequiresElevation() (bool, error) {
+	return updater.requiresElevation(dirWritable)
+}
+
+// requiresElevation checks whether the install location is writable with
+// writable, which either tries writing to a directory or only checks its
+// permissions.
+func (updater *Updater) requiresElevation(writable func(dir string) bool) (bool, error) {
+	target, err := updater.installTarget()
+	if err != nil {
+		return false, err
+	}
+
+	if protectedPath(target) {
+		return true, nil
+	}
+
+	// Swapping renames the target within its directory, which requires write
+	// access to the directory rather than to the target itself.
+	dir, err := existingDir(filepath.Dir(target))
+	if err != nil {
+		return false, err
+	}
+
+	return !writable(dir), nil
+}
+
+// existingDir returns dir or the closest ancestor of dir that exists, which is
+// where missing directories would be created.
+func existingDir(dir string) (string, error) {
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
+
+func dirWritable(dir string) bool {
+	file, err := os.CreateTemp(dir, ".updater-")
+	if err != nil {
+		return false
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return true
+}
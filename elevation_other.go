@@ -0,0 +1,7 @@
+//go:build !windows
+
+package updater
+
+func protectedPath(path string) bool {
+	return false
+}
@@ -0,0 +1,32 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// protectedPath reports whether path is under Program Files while the process
+// is not elevated. Writes there may succeed through UAC file virtualization
+// without updating the installed binary.
+func protectedPath(path string) bool {
+	if windows.GetCurrentProcessToken().IsElevated() {
+		return false
+	}
+
+	for _, variable := range []string{"ProgramFiles", "ProgramFiles(x86)", "ProgramW6432"} {
+		root := os.Getenv(variable)
+		if root == "" {
+			continue
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
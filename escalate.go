@@ -0,0 +1,92 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var ErrElevationRequired = errors.New("Installing the update requires elevated privileges")
+
+// ElevationStrategy selects what happens when the target binary cannot be
+// replaced by the current user.
+type ElevationStrategy string
+
+const (
+	// ElevationFail fails the update with ErrElevationRequired.
+	ElevationFail ElevationStrategy = "fail"
+	// ElevationPrompt replaces the binary with elevated privileges, with sudo
+	// or pkexec on Unix and a UAC prompt on Windows.
+	ElevationPrompt ElevationStrategy = "prompt"
+	// ElevationUserDir installs the update to the UserInstallDir instead.
+	ElevationUserDir ElevationStrategy = "userDir"
+)
+
+// userInstallDir defaults to ~/.local/bin on Unix and to a directory named
+// after the executable in %LOCALAPPDATA%\Programs on Windows.
+func (updater *Updater) userInstallDir(binaryPath string) (string, error) {
+	if updater.config.UserInstallDir != "" {
+		return updater.config.UserInstallDir, nil
+	}
+
+	if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" && filepath.Ext(binaryPath) == ".exe" {
+		name := filepath.Base(binaryPath)
+		return filepath.Join(localAppData, "Programs", name[:len(name)-len(".exe")]), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "bin"), nil
+}
+
+// installElevated replaces the binary at binaryPath, which the current user
+// cannot move, according to the Elevation strategy.
+func (updater *Updater) installElevated(stagedPath string, installed *installation, mode os.FileMode, permissionErr error) error {
+	binaryPath := installed.target
+	switch updater.config.Elevation {
+	case ElevationPrompt:
+		updater.logger().Info("Installing with elevated privileges", "target", binaryPath)
+		backupPath := updater.backupPath(binaryPath)
+		err := elevatedInstall(stagedPath, binaryPath, backupPath, mode)
+		if err != nil {
+			return fmt.Errorf("%w. %w", ErrElevationRequired, err)
+		}
+		os.Remove(stagedPath)
+		installed.backupPath = backupPath
+		installed.elevated = true
+		return nil
+	case ElevationUserDir:
+		dir, err := updater.userInstallDir(binaryPath)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		userPath := filepath.Join(dir, filepath.Base(binaryPath))
+		updater.logger().Info("Installing to the user install directory", "target", userPath)
+		err = moveFile(stagedPath, userPath)
+		if err == nil {
+			err = os.Chmod(userPath, mode)
+		}
+		if err != nil {
+			return err
+		}
+		installed.target = userPath
+		installed.binaryPath = userPath
+		installed.backupPath = ""
+		return nil
+	default:
+		return fmt.Errorf("%w. %s cannot be replaced. %w", ErrElevationRequired, binaryPath, permissionErr)
+	}
+}
+
+func permissionDenied(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
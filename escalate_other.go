@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package updater
+
+import (
+	"os"
+	"runtime"
+)
+
+func elevatedInstall(stagedPath string, binaryPath string, backupPath string, mode os.FileMode) error {
+	return &NotSupportedError{Platform: runtime.GOOS}
+}
+
+func elevatedRestore(backupPath string, binaryPath string) error {
+	return &NotSupportedError{Platform: runtime.GOOS}
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin || freebsd
+
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// elevatedCommand runs the shell script as root, with sudo when attached to a
+// terminal and with pkexec, polkit, otherwise.
+func elevatedCommand(script string, args ...string) error {
+	var tool string
+	stat, err := os.Stdin.Stat()
+	if err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		tool, err = exec.LookPath("sudo")
+	} else {
+		tool, err = exec.LookPath("pkexec")
+	}
+	if err != nil {
+		return errors.New("Neither a terminal for sudo nor pkexec is available")
+	}
+
+	cmd := exec.Command(tool, append([]string{"/bin/sh", "-c", script, "sh"}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("%w. %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// elevatedInstall moves the binary to backupPath and installs the staged
+// binary in its place, owned by root, moving the binary back on failure.
+func elevatedInstall(stagedPath string, binaryPath string, backupPath string, mode os.FileMode) error {
+	return elevatedCommand(`mv -f "$1" "$2" && { install -m "$4" "$3" "$1" || { mv -f "$2" "$1"; exit 1; }; }`, binaryPath, backupPath, stagedPath, fmt.Sprintf("%04o", mode.Perm()))
+}
+
+func elevatedRestore(backupPath string, binaryPath string) error {
+	return elevatedCommand(`mv -f "$1" "$2"`, backupPath, binaryPath)
+}
@@ -0,0 +1,93 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	seeMaskNoCloseProcess = 0x00000040
+	seeMaskNoAsync        = 0x00000100
+)
+
+var procShellExecuteExW = windows.NewLazySystemDLL("shell32.dll").NewProc("ShellExecuteExW")
+
+// shellExecuteInfo is SHELLEXECUTEINFOW.
+type shellExecuteInfo struct {
+	size       uint32
+	mask       uint32
+	hwnd       windows.Handle
+	verb       *uint16
+	file       *uint16
+	parameters *uint16
+	directory  *uint16
+	show       int32
+	instApp    windows.Handle
+	idList     uintptr
+	class      *uint16
+	keyClass   windows.Handle
+	hotKey     uint32
+	icon       windows.Handle
+	process    windows.Handle
+}
+
+// elevatedCommand runs cmd.exe with the given arguments after a UAC prompt and
+// waits for it to exit.
+func elevatedCommand(parameters string) error {
+	verb, err := windows.UTF16PtrFromString("runas")
+	if err != nil {
+		return err
+	}
+	file, err := windows.UTF16PtrFromString(filepath.Join(os.Getenv("SystemRoot"), "System32", "cmd.exe"))
+	if err != nil {
+		return err
+	}
+	params, err := windows.UTF16PtrFromString(parameters)
+	if err != nil {
+		return err
+	}
+
+	info := shellExecuteInfo{
+		mask:       seeMaskNoCloseProcess | seeMaskNoAsync,
+		verb:       verb,
+		file:       file,
+		parameters: params,
+		show:       windows.SW_HIDE,
+	}
+	info.size = uint32(unsafe.Sizeof(info))
+	ok, _, err := procShellExecuteExW.Call(uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return err
+	}
+	defer windows.CloseHandle(info.process)
+
+	_, err = windows.WaitForSingleObject(info.process, windows.INFINITE)
+	if err != nil {
+		return err
+	}
+	var exitCode uint32
+	err = windows.GetExitCodeProcess(info.process, &exitCode)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("Elevated command exited with code %d", exitCode)
+	}
+
+	return nil
+}
+
+// elevatedInstall moves the binary to backupPath and copies the staged binary
+// in its place, moving the binary back on failure.
+func elevatedInstall(stagedPath string, binaryPath string, backupPath string, mode os.FileMode) error {
+	return elevatedCommand(fmt.Sprintf(`/c move /y "%s" "%s" >nul && (copy /y "%s" "%s" >nul || (move /y "%s" "%s" >nul & exit /b 1))`,
+		binaryPath, backupPath, stagedPath, binaryPath, backupPath, binaryPath))
+}
+
+func elevatedRestore(backupPath string, binaryPath string) error {
+	return elevatedCommand(fmt.Sprintf(`/c move /y "%s" "%s" >nul`, backupPath, binaryPath))
+}
@@ -0,0 +1,71 @@
+package updater
+
+type EventType int
+
+const (
+	// EventCheckStarted is emitted when a check for updates, or an update,
+	// starts fetching the manifest.
+	EventCheckStarted EventType = iota
+	// EventUpdateAvailable is emitted when a check finds an update.
+	EventUpdateAvailable
+	// EventDownloadStarted is emitted before each download of Name, once per
+	// candidate url.
+	EventDownloadStarted
+	// EventDownloadProgress is emitted as Name is downloaded.
+	EventDownloadProgress
+	// EventVerified is emitted once the update is downloaded and verified.
+	EventVerified
+	// EventApplied is emitted once the update is installed.
+	EventApplied
+	// EventRolledBack is emitted when the previous version is restored, by
+	// Rollback or because installing the update failed with Err.
+	EventRolledBack
+	// EventFailed is emitted when a check or an update fails with Err.
+	EventFailed
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventCheckStarted:
+		return "check-started"
+	case EventUpdateAvailable:
+		return "update-available"
+	case EventDownloadStarted:
+		return "download-started"
+	case EventDownloadProgress:
+		return "download-progress"
+	case EventVerified:
+		return "verified"
+	case EventApplied:
+		return "applied"
+	case EventRolledBack:
+		return "rolled-back"
+	case EventFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a step of the update lifecycle. Fields that do not apply to
+// the Type are left empty.
+type Event struct {
+	Type EventType
+	// Version is the version being checked for, downloaded or installed, or
+	// the version restored by Rollback.
+	Version string
+	// Name is the archive, binary or patch being downloaded.
+	Name string
+	// BytesDownloaded and TotalBytes, -1 when unknown, report the progress of
+	// downloads.
+	BytesDownloaded int64
+	TotalBytes      int64
+	Err             error
+}
+
+// emit sends the event to OnEvent, on the goroutine running the update.
+func (updater *Updater) emit(event Event) {
+	if updater.config.OnEvent != nil {
+		updater.config.OnEvent(event)
+	}
+}
@@ -0,0 +1,175 @@
+package updater
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+type exportedState struct {
+	PinnedKey string         `json:"pinnedKey,omitempty"`
+	Manifest  *manifestCache `json:"manifest,omitempty"`
+	// Artifacts are the cached artifacts, keyed like the cache index.
+	Artifacts map[string]exportedArtifact `json:"artifacts,omitempty"`
+}
+
+type exportedArtifact struct {
+	Sha256 string `json:"sha256"`
+	Data   []byte `json:"data"`
+}
+
+// ExportState serializes the updater's state so it can be baked into a base
+// image and restored on each machine with ImportState: the pinned key, the
+// cached manifest when CacheManifest is set and the cached artifacts of
+// CacheDir.
+func (updater *Updater) ExportState() ([]byte, error) {
+	var state exportedState
+
+	if updater.config.PinnedKeyPath != "" {
+		key, err := updater.readPinnedKey()
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			state.PinnedKey = base64.StdEncoding.EncodeToString(key)
+		}
+	}
+
+	if updater.config.CacheManifest {
+		cached, err := updater.exportManifestCache()
+		if err != nil {
+			return nil, err
+		}
+		state.Manifest = cached
+	}
+
+	if updater.config.CacheDir != "" {
+		artifacts, err := updater.exportArtifacts()
+		if err != nil {
+			return nil, err
+		}
+		state.Artifacts = artifacts
+	}
+
+	return json.Marshal(state)
+}
+
+func (updater *Updater) exportManifestCache() (*manifestCache, error) {
+	path, err := updater.manifestCachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cached manifestCache
+	err = json.Unmarshal(data, &cached)
+	if err != nil {
+		// A corrupt cache is not worth exporting.
+		return nil, nil
+	}
+
+	return &cached, nil
+}
+
+// exportArtifacts returns the cached artifacts that still match their
+// checksum.
+func (updater *Updater) exportArtifacts() (map[string]exportedArtifact, error) {
+	index, err := updater.readCacheIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := make(map[string]exportedArtifact, len(index))
+	for key, checksum := range index {
+		cachedPath := filepath.Join(updater.config.CacheDir, checksum)
+		actual, err := fileSha256(cachedPath)
+		if err != nil || actual != checksum {
+			continue
+		}
+		data, err := os.ReadFile(cachedPath)
+		if err != nil {
+			return nil, err
+		}
+		artifacts[key] = exportedArtifact{Sha256: checksum, Data: data}
+	}
+
+	return artifacts, nil
+}
+
+// ImportState restores the state serialized by ExportState. Imported
+// artifacts must match their checksum.
+func (updater *Updater) ImportState(data []byte) error {
+	var state exportedState
+	err := json.Unmarshal(data, &state)
+	if err != nil {
+		return fmt.Errorf("Invalid updater state. %w", err)
+	}
+
+	if state.PinnedKey != "" {
+		if updater.config.PinnedKeyPath == "" {
+			return fmt.Errorf("Cannot import pinned key. PinnedKeyPath is not configured")
+		}
+		key, err := parsePublicKey(state.PinnedKey)
+		if err != nil {
+			return err
+		}
+		err = updater.pinKey(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if state.Manifest != nil {
+		if !updater.config.CacheManifest {
+			return fmt.Errorf("Cannot import the cached manifest. CacheManifest is not set")
+		}
+		err = updater.writeManifestCache(state.Manifest)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(state.Artifacts) > 0 {
+		if updater.config.CacheDir == "" {
+			return fmt.Errorf("Cannot import cached artifacts. CacheDir is not configured")
+		}
+		for key, artifact := range state.Artifacts {
+			err = updater.importArtifact(key, artifact)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (updater *Updater) importArtifact(key string, artifact exportedArtifact) error {
+	tempFile := filepath.Join(os.TempDir(), uuid.NewString())
+	err := os.WriteFile(tempFile, artifact.Data, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile)
+
+	actual, err := fileSha256(tempFile)
+	if err != nil {
+		return err
+	}
+	if actual != artifact.Sha256 {
+		return fmt.Errorf("%w for the cached artifact %s. Expected %s but got %s", ErrChecksumMismatch, key, artifact.Sha256, actual)
+	}
+
+	return updater.cacheArtifact(key, actual, tempFile)
+}
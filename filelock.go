@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// fileLock is an advisory lock held by one process at a time, so that two
+// instances of the same application cannot swap the binary concurrently.
+type fileLock struct {
+	file *os.File
+}
+
+// lockPath is <binary>.update.lock next to the binary, or the install
+// directory.
+func (updater *Updater) lockPath() (string, error) {
+	target, err := updater.installTarget()
+	if err != nil {
+		return "", err
+	}
+
+	return target + ".update.lock", nil
+}
+
+// lockUpdate takes the update lock of the install target, or returns
+// ErrUpdateInProgress when another process holds it. When the lock cannot be
+// created next to the target, e.g., elevation is needed, the lock is in the
+// temp dir instead, named after the target.
+func (updater *Updater) lockUpdate() (*fileLock, error) {
+	path, err := updater.lockPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+		key := sha256.Sum256([]byte(path))
+		file, err = os.OpenFile(filepath.Join(os.TempDir(), "updater-"+hex.EncodeToString(key[:16])+".update.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := lockFile(file)
+	if err != nil || !locked {
+		file.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, fmt.Errorf("%w in another process", ErrUpdateInProgress)
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+func (lock *fileLock) unlock() {
+	_ = unlockFile(lock.file)
+	lock.file.Close()
+}
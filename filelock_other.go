@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package updater
+
+import "os"
+
+// lockFile does not lock, advisory locks are not supported on this platform.
+func lockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
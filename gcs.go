@@ -0,0 +1,310 @@
+package updater
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcsReadOnlyScope   = "https://www.googleapis.com/auth/devstorage.read_only"
+	googleTokenUrl     = "https://oauth2.googleapis.com/token"
+	gceMetadataHost    = "metadata.google.internal"
+	defaultGcsEndpoint = "https://storage.googleapis.com"
+)
+
+// GCSSource reads the manifest and assets from a Google Cloud Storage bucket
+// using application default credentials, or signed urls.
+type GCSSource struct {
+	Bucket string
+	// Prefix is prepended to the manifest and asset names, e.g., "releases/".
+	Prefix string
+	// Manifest is the name of the manifest object, relative to Prefix.
+	Manifest string
+	// SignedUrl returns a signed url for the object, e.g., minted by a
+	// backend, for clients without credentials of their own. When set,
+	// objects are downloaded from the signed urls without credentials.
+	SignedUrl func(ctx context.Context, object string) (string, error)
+	// Endpoint defaults to https://storage.googleapis.com.
+	Endpoint string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	tokenMu sync.Mutex
+	token   *oauthToken
+}
+
+type oauthToken struct {
+	accessToken string
+	expiry      time.Time
+}
+
+func (token *oauthToken) valid() bool {
+	return token != nil && time.Now().Add(time.Minute).Before(token.expiry)
+}
+
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenUri     string `json:"token_uri"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (source *GCSSource) client() *http.Client {
+	if source.Client != nil {
+		return source.Client
+	}
+
+	return http.DefaultClient
+}
+
+func (source *GCSSource) FetchManifest(ctx context.Context) (io.ReadCloser, int64, error) {
+	return source.FetchAsset(ctx, source.Manifest)
+}
+
+func (source *GCSSource) FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	object := strings.TrimPrefix(source.Prefix+name, "/")
+
+	var request *http.Request
+	var err error
+	if source.SignedUrl != nil {
+		signedUrl, err := source.SignedUrl(ctx, object)
+		if err != nil {
+			return nil, 0, err
+		}
+		request, err = http.NewRequestWithContext(ctx, "GET", signedUrl, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		endpoint := defaultGcsEndpoint
+		if source.Endpoint != "" {
+			endpoint = strings.TrimSuffix(source.Endpoint, "/")
+		}
+		request, err = http.NewRequestWithContext(ctx, "GET", endpoint+"/"+url.PathEscape(source.Bucket)+"/"+escapeObjectPath(object), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		token, err := source.accessToken(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("Error downloading gs://%s/%s. %w", source.Bucket, object, httpStatusError(resp))
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+func escapeObjectPath(object string) string {
+	segments := strings.Split(object, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func (source *GCSSource) accessToken(ctx context.Context) (string, error) {
+	source.tokenMu.Lock()
+	defer source.tokenMu.Unlock()
+
+	if source.token.valid() {
+		return source.token.accessToken, nil
+	}
+
+	token, err := source.defaultCredentialsToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	source.token = token
+
+	return token.accessToken, nil
+}
+
+// defaultCredentialsToken resolves application default credentials from
+// GOOGLE_APPLICATION_CREDENTIALS, the gcloud credentials file or the compute
+// metadata server.
+func (source *GCSSource) defaultCredentialsToken(ctx context.Context) (*oauthToken, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = gcloudCredentialsPath()
+		if _, err := os.Stat(path); err != nil {
+			path = ""
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var credentials googleCredentials
+		err = json.Unmarshal(data, &credentials)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Google credentials %s. %w", path, err)
+		}
+
+		switch credentials.Type {
+		case "service_account":
+			return source.serviceAccountToken(ctx, &credentials)
+		case "authorized_user":
+			return source.refreshToken(ctx, &credentials)
+		default:
+			return nil, fmt.Errorf("Unsupported Google credentials type %q in %s", credentials.Type, path)
+		}
+	}
+
+	token, err := source.metadataToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("No Google application default credentials found. %w", err)
+	}
+
+	return token, nil
+}
+
+func gcloudCredentialsPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+func (source *GCSSource) requestToken(request *http.Request) (*oauthToken, error) {
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Error requesting an access token from %s. %w", request.URL, httpStatusError(resp))
+	}
+
+	var token tokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid access token response from %s. %w", request.URL, err)
+	}
+
+	return &oauthToken{accessToken: token.AccessToken, expiry: time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)}, nil
+}
+
+func (source *GCSSource) postToken(ctx context.Context, tokenUrl string, form url.Values) (*oauthToken, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return source.requestToken(request)
+}
+
+// serviceAccountToken exchanges a JWT signed with the service account key for
+// an access token.
+func (source *GCSSource) serviceAccountToken(ctx context.Context, credentials *googleCredentials) (*oauthToken, error) {
+	block, _ := pem.Decode([]byte(credentials.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("Invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid service account private key. %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Invalid service account private key. Expected an RSA key")
+	}
+
+	tokenUrl := credentials.TokenUri
+	if tokenUrl == "" {
+		tokenUrl = googleTokenUrl
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   credentials.ClientEmail,
+		"scope": gcsReadOnlyScope,
+		"aud":   tokenUrl,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return source.postToken(ctx, tokenUrl, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	})
+}
+
+func (source *GCSSource) refreshToken(ctx context.Context, credentials *googleCredentials) (*oauthToken, error) {
+	return source.postToken(ctx, googleTokenUrl, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {credentials.ClientId},
+		"client_secret": {credentials.ClientSecret},
+		"refresh_token": {credentials.RefreshToken},
+	})
+}
+
+func (source *GCSSource) metadataToken(ctx context.Context) (*oauthToken, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = gceMetadataHost
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token?scopes="+url.QueryEscape(gcsReadOnlyScope), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+
+	return source.requestToken(request)
+}
@@ -0,0 +1,117 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GiteaSource resolves updates from the latest release of a Gitea or Forgejo
+// repository.
+type GiteaSource struct {
+	// Url is the url of the Gitea instance, e.g., https://gitea.example.com.
+	Url   string
+	Owner string
+	Repo  string
+	// Token is an access token, e.g., for private repositories.
+	Token string
+	// Binary is the name of the binary within archive assets, a template like
+	// the manifest binary. Defaults to the repository name.
+	Binary string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	assets releaseAssets
+}
+
+type giteaAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+type giteaRelease struct {
+	TagName     string       `json:"tag_name"`
+	Body        string       `json:"body"`
+	PublishedAt time.Time    `json:"published_at"`
+	HtmlUrl     string       `json:"html_url"`
+	Assets      []giteaAsset `json:"assets"`
+}
+
+func (source *GiteaSource) client() *http.Client {
+	if source.Client != nil {
+		return source.Client
+	}
+
+	return http.DefaultClient
+}
+
+// get authenticates requests to the Gitea instance. The Authorization header
+// is not forwarded when redirected to other hosts.
+func (source *GiteaSource) get(ctx context.Context, requestUrl string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := url.Parse(source.Url)
+	if err == nil && request.URL.Host == instance.Host && source.Token != "" {
+		request.Header.Set("Authorization", "token "+source.Token)
+	}
+
+	return source.client().Do(request)
+}
+
+func (source *GiteaSource) fetch(ctx context.Context, requestUrl string) ([]byte, error) {
+	reader, size, err := readResponse(source.get(ctx, requestUrl))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readLimited(requestUrl, size, reader, maxSidecarBytes)
+}
+
+func (source *GiteaSource) FetchManifest(ctx context.Context) (io.ReadCloser, int64, error) {
+	releaseUrl := fmt.Sprintf("%s/api/v1/repos/%s/%s/releases/latest", strings.TrimSuffix(source.Url, "/"), url.PathEscape(source.Owner), url.PathEscape(source.Repo))
+	data, err := source.fetch(ctx, releaseUrl)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var release giteaRelease
+	err = json.Unmarshal(data, &release)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Invalid Gitea release. %w", err)
+	}
+
+	urls := make(map[string]string)
+	for _, asset := range release.Assets {
+		urls[asset.Name] = asset.BrowserDownloadUrl
+	}
+	source.assets.set(urls)
+
+	details := forgeRelease{version: release.TagName, notes: release.Body, publishedAt: release.PublishedAt, url: release.HtmlUrl}
+	manifest, err := releaseManifestJson(ctx, details, urls, source.Binary, source.Repo, source.fetch)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return io.NopCloser(bytes.NewReader(manifest)), int64(len(manifest)), nil
+}
+
+// FetchAsset downloads an asset of the release returned by the last
+// FetchManifest call.
+func (source *GiteaSource) FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	assetUrl, err := source.assets.url(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return readResponse(source.get(ctx, assetUrl))
+}
@@ -0,0 +1,261 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const defaultGitHubApiUrl = "https://api.github.com"
+
+// GitHubSource resolves updates from the latest GitHub release of a
+// repository instead of a hosted manifest.
+type GitHubSource struct {
+	Owner string
+	Repo  string
+	// Token authenticates API and asset requests, e.g., for private
+	// repositories or higher rate limits.
+	Token string
+	// Binary is the name of the binary within archive assets, a template like
+	// the manifest binary. Defaults to the repository name.
+	Binary string
+	// ApiUrl is the GitHub API url. Defaults to https://api.github.com, set
+	// it for GitHub Enterprise Server.
+	ApiUrl string
+}
+
+type gitHubAsset struct {
+	Name               string `json:"name"`
+	Url                string `json:"url"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+type gitHubRelease struct {
+	TagName     string        `json:"tag_name"`
+	Body        string        `json:"body"`
+	PublishedAt time.Time     `json:"published_at"`
+	HtmlUrl     string        `json:"html_url"`
+	Assets      []gitHubAsset `json:"assets"`
+}
+
+var gitHubOsAliases = map[string][]string{
+	"darwin":  {"darwin", "macos", "mac", "osx", "apple"},
+	"windows": {"windows", "win"},
+}
+
+var gitHubArchAliases = map[string][]string{
+	"amd64": {"amd64", "x64", "64bit"},
+	"arm64": {"arm64", "aarch64"},
+	"386":   {"386", "i386", "i686", "x86", "32bit"},
+	"arm":   {"arm", "armv6", "armv7", "armhf"},
+}
+
+// gitHubSkippedSuffixes are release assets that are never the update itself.
+var gitHubSkippedSuffixes = []string{
+	".sig", ".minisig", ".asc", ".pem", ".sbom", ".json", ".txt", ".sha256", ".sha512",
+	".deb", ".rpm", ".apk", ".msi", ".dmg", ".pkg",
+}
+
+func (source *GitHubSource) apiUrl() string {
+	if source.ApiUrl != "" {
+		return strings.TrimSuffix(source.ApiUrl, "/")
+	}
+
+	return defaultGitHubApiUrl
+}
+
+func (source *GitHubSource) prepareRequest(request *http.Request) {
+	apiUrl, err := url.Parse(source.apiUrl())
+	if err != nil || request.URL.Host != apiUrl.Host {
+		return
+	}
+
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if strings.Contains(request.URL.Path, "/releases/assets/") {
+		request.Header.Set("Accept", "application/octet-stream")
+	} else {
+		request.Header.Set("Accept", "application/vnd.github+json")
+	}
+	if source.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+source.Token)
+	}
+}
+
+// assetTokens splits an asset name into lower case words, treating x86_64 as
+// a single word.
+func assetTokens(name string) map[string]bool {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("x86_64", "amd64", "x86-64", "amd64").Replace(name)
+
+	tokens := make(map[string]bool)
+	for _, token := range strings.FieldsFunc(name, func(char rune) bool {
+		return char == '-' || char == '_' || char == '.' || char == ' '
+	}) {
+		tokens[token] = true
+	}
+
+	return tokens
+}
+
+func hasAnyToken(tokens map[string]bool, candidates []string) bool {
+	for _, candidate := range candidates {
+		if tokens[candidate] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func aliases(table map[string][]string, name string) []string {
+	if names, ok := table[name]; ok {
+		return names
+	}
+
+	return []string{name}
+}
+
+// selectReleaseAsset picks the release asset for the platform by the os and
+// arch names commonly used in asset names, preferring archives over raw
+// binaries and assets built for the detected libc.
+func selectReleaseAsset(names []string, goos string, goarch string, libc string) (string, error) {
+	selected := ""
+	selectedScore := 0
+
+	for _, name := range names {
+		lowerName := strings.ToLower(name)
+		skipped := false
+		for _, suffix := range gitHubSkippedSuffixes {
+			skipped = skipped || strings.HasSuffix(lowerName, suffix)
+		}
+		if skipped {
+			continue
+		}
+
+		tokens := assetTokens(name)
+		if !hasAnyToken(tokens, aliases(gitHubOsAliases, goos)) {
+			continue
+		}
+		universal := goos == "darwin" && hasAnyToken(tokens, []string{"all", "universal"})
+		if !hasAnyToken(tokens, aliases(gitHubArchAliases, goarch)) && !universal {
+			continue
+		}
+
+		score := 1
+		if isArchive(name) {
+			score += 2
+		}
+		if tokens[LibcMusl] == (libc == LibcMusl) {
+			score++
+		}
+		if score > selectedScore {
+			selected = name
+			selectedScore = score
+		}
+	}
+
+	if selected == "" {
+		return "", &NotSupportedError{Platform: fmt.Sprintf("%s/%s", goos, goarch)}
+	}
+
+	return selected, nil
+}
+
+// releaseManifest builds the manifest for the selected asset of a release.
+// binary is the name of the binary within archives, defaulting to name.
+func releaseManifest(version string, asset string, binary string, name string) *UpdaterManifest {
+	manifest := &UpdaterManifest{
+		Version: version,
+		Os:      map[string]string{runtime.GOOS: runtime.GOOS},
+		Arch:    map[string]map[string]string{runtime.GOOS: {runtime.GOARCH: runtime.GOARCH}},
+	}
+
+	if isArchive(asset) {
+		if binary == "" {
+			binary = name + "{{.Ext}}"
+		}
+		manifest.Archive = templateLiteral(asset)
+		manifest.Binary = binary
+	} else {
+		manifest.Binary = templateLiteral(asset)
+	}
+
+	return manifest
+}
+
+// isChecksumsAsset reports whether the asset is a checksums file as
+// published by GoReleaser.
+func isChecksumsAsset(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), "checksums.txt")
+}
+
+func (source *GitHubSource) assetUrl(asset gitHubAsset) string {
+	if source.Token != "" && asset.Url != "" {
+		return asset.Url
+	}
+
+	return asset.BrowserDownloadUrl
+}
+
+// manifest builds a manifest for the latest release. The urls of all release
+// assets are kept in the manifest so that signatures and checksums published
+// alongside the assets are found as well.
+func (source *GitHubSource) manifest(ctx context.Context, updater *Updater) (*UpdaterManifest, error) {
+	releaseUrl := fmt.Sprintf("%s/repos/%s/%s/releases/latest", source.apiUrl(), url.PathEscape(source.Owner), url.PathEscape(source.Repo))
+	data, err := updater.fetchUrl(ctx, releaseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var release gitHubRelease
+	err = json.Unmarshal(data, &release)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid GitHub release. %w", err)
+	}
+
+	names := make([]string, len(release.Assets))
+	for i, releaseAsset := range release.Assets {
+		names[i] = releaseAsset.Name
+	}
+	asset, err := selectReleaseAsset(names, runtime.GOOS, runtime.GOARCH, detectLibc())
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := releaseManifest(release.TagName, asset, source.Binary, source.Repo)
+	manifest.ReleaseNotes = release.Body
+	manifest.PublishedAt = release.PublishedAt
+	manifest.Url = release.HtmlUrl
+	manifest.assetUrls = make(map[string]string)
+	for _, releaseAsset := range release.Assets {
+		manifest.assetUrls[releaseAsset.Name] = source.assetUrl(releaseAsset)
+	}
+
+	for _, releaseAsset := range release.Assets {
+		if isChecksumsAsset(releaseAsset.Name) {
+			data, err := updater.fetchUrl(ctx, source.assetUrl(releaseAsset))
+			if err != nil {
+				return nil, err
+			}
+			manifest.Checksums = parseChecksums(data)
+			break
+		}
+	}
+
+	return manifest, nil
+}
+
+// templateLiteral quotes name so that it renders as is.
+func templateLiteral(name string) string {
+	if !strings.Contains(name, "{{") {
+		return name
+	}
+
+	quoted, _ := json.Marshal(name)
+	return "{{" + string(quoted) + "}}"
+}
@@ -0,0 +1,158 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultGitLabUrl = "https://gitlab.com"
+
+// GitLabSource resolves updates from the latest release of a GitLab project,
+// on gitlab.com or a self-hosted instance.
+type GitLabSource struct {
+	// Project is the project id or its full path, e.g., "group/project".
+	Project string
+	// Token is a personal, project or group access token sent in the
+	// PRIVATE-TOKEN header, e.g., for private projects or generic packages.
+	Token string
+	// Binary is the name of the binary within archive assets, a template like
+	// the manifest binary. Defaults to the project name.
+	Binary string
+	// Url is the url of the GitLab instance. Defaults to https://gitlab.com.
+	Url string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	assets releaseAssets
+}
+
+type gitLabLink struct {
+	Name           string `json:"name"`
+	Url            string `json:"url"`
+	DirectAssetUrl string `json:"direct_asset_url"`
+}
+
+type gitLabRelease struct {
+	TagName     string    `json:"tag_name"`
+	Description string    `json:"description"`
+	ReleasedAt  time.Time `json:"released_at"`
+	Assets      struct {
+		Links []gitLabLink `json:"links"`
+	} `json:"assets"`
+	Links struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+}
+
+func (source *GitLabSource) instanceUrl() string {
+	if source.Url != "" {
+		return strings.TrimSuffix(source.Url, "/")
+	}
+
+	return defaultGitLabUrl
+}
+
+// client returns a client that never forwards the PRIVATE-TOKEN header to
+// other hosts, e.g., when asset links redirect to object storage.
+func (source *GitLabSource) client() *http.Client {
+	client := http.Client{}
+	if source.Client != nil {
+		client = *source.Client
+	}
+
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if request.URL.Host != via[0].URL.Host {
+			request.Header.Del("PRIVATE-TOKEN")
+		}
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	return &client
+}
+
+func (source *GitLabSource) get(ctx context.Context, requestUrl string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := url.Parse(source.instanceUrl())
+	if err == nil && request.URL.Host == instance.Host && source.Token != "" {
+		request.Header.Set("PRIVATE-TOKEN", source.Token)
+	}
+
+	return source.client().Do(request)
+}
+
+func (source *GitLabSource) fetch(ctx context.Context, requestUrl string) ([]byte, error) {
+	reader, size, err := readResponse(source.get(ctx, requestUrl))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readLimited(requestUrl, size, reader, maxSidecarBytes)
+}
+
+func (source *GitLabSource) FetchManifest(ctx context.Context) (io.ReadCloser, int64, error) {
+	releasesUrl := fmt.Sprintf("%s/api/v4/projects/%s/releases?order_by=released_at&sort=desc&per_page=1", source.instanceUrl(), url.PathEscape(source.Project))
+	data, err := source.fetch(ctx, releasesUrl)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var releases []gitLabRelease
+	err = json.Unmarshal(data, &releases)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Invalid GitLab releases. %w", err)
+	}
+	if len(releases) == 0 {
+		return nil, 0, fmt.Errorf("GitLab project %s has no releases", source.Project)
+	}
+	release := releases[0]
+
+	urls := make(map[string]string)
+	for _, link := range release.Assets.Links {
+		if link.DirectAssetUrl != "" {
+			urls[link.Name] = link.DirectAssetUrl
+		} else {
+			urls[link.Name] = link.Url
+		}
+	}
+	source.assets.set(urls)
+
+	name := source.Project[strings.LastIndex(source.Project, "/")+1:]
+	details := forgeRelease{version: release.TagName, notes: release.Description, publishedAt: release.ReleasedAt, url: release.Links.Self}
+	manifest, err := releaseManifestJson(ctx, details, urls, source.Binary, name, source.fetch)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return io.NopCloser(bytes.NewReader(manifest)), int64(len(manifest)), nil
+}
+
+// FetchAsset downloads an asset link of the release returned by the last
+// FetchManifest call.
+func (source *GitLabSource) FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	assetUrl, err := source.assets.url(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return readResponse(source.get(ctx, assetUrl))
+}
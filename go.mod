@@ -2,4 +2,18 @@ module github.com/dworthen/updater
 
 go 1.21.5
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.17.11
+	github.com/spf13/cobra v1.8.1
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/crypto v0.27.0
+	golang.org/x/sys v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+)
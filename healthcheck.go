@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var ErrHealthCheckFailed = errors.New("Health check failed")
+
+const defaultHealthCheckTimeout = 10 * time.Second
+
+// runHealthCheck runs the newly installed binary with HealthCheckArgs and
+// expects it to exit successfully and print the manifest version, with or
+// without a leading v.
+func (updater *Updater) runHealthCheck(info *downloadInfo, installed *installation) error {
+	if !updater.config.HealthCheck {
+		return nil
+	}
+
+	args := updater.config.HealthCheckArgs
+	if len(args) == 0 {
+		args = []string{"--version"}
+	}
+	timeout := updater.config.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, installed.binaryPath, args...).CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w. %s did not exit within %s", ErrHealthCheckFailed, installed.binaryPath, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w. %w. %s", ErrHealthCheckFailed, err, strings.TrimSpace(string(output)))
+	}
+
+	version := strings.TrimPrefix(strings.TrimSpace(info.manifest.Version), "v")
+	if !strings.Contains(string(output), version) {
+		return fmt.Errorf("%w. Expected the output of %s to include version %s but got %q", ErrHealthCheckFailed, installed.binaryPath, version, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
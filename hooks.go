@@ -0,0 +1,49 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrHookFailed = errors.New("Update hook failed")
+
+func newUpdateInfo(manifest *UpdaterManifest) *UpdateInfo {
+	return &UpdateInfo{
+		Version:      strings.TrimSpace(manifest.Version),
+		ReleaseNotes: manifest.ReleaseNotes,
+		PublishedAt:  manifest.PublishedAt,
+		Url:          manifest.Url,
+		Manifest:     manifest,
+	}
+}
+
+// runBeforeApply runs the BeforeApply hook once the update is ready to be
+// installed.
+func (updater *Updater) runBeforeApply(manifest *UpdaterManifest) error {
+	if updater.config.BeforeApply == nil {
+		return nil
+	}
+
+	err := updater.config.BeforeApply(*newUpdateInfo(manifest))
+	if err != nil {
+		return fmt.Errorf("%w. BeforeApply: %w", ErrHookFailed, err)
+	}
+
+	return nil
+}
+
+// runAfterApply runs the AfterApply hook once the update is installed, before
+// the backup of the previous version is discarded.
+func (updater *Updater) runAfterApply(manifest *UpdaterManifest) error {
+	if updater.config.AfterApply == nil {
+		return nil
+	}
+
+	err := updater.config.AfterApply(*newUpdateInfo(manifest))
+	if err != nil {
+		return fmt.Errorf("%w. AfterApply: %w", ErrHookFailed, err)
+	}
+
+	return nil
+}
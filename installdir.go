@@ -0,0 +1,168 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+func (updater *Updater) downloadArchiveDir(ctx context.Context, info *downloadInfo) (*stagedUpdate, error) {
+	tempFile, err := updater.download(ctx, info, func() string { return info.archiveName })
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempFile)
+
+	installDir := filepath.Clean(updater.config.InstallDir)
+	err = os.MkdirAll(filepath.Dir(installDir), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	stagingDir := installDir + ".new-" + uuid.NewString()
+	err = os.Mkdir(stagingDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	staged := &stagedUpdate{path: stagingDir, dir: true}
+
+	_, span := updater.startSpan(ctx, "updater.Extract", slog.String("updater.asset.name", info.archiveName))
+	if isTarball(info.archiveName) {
+		err = updater.extractTarballTo(info.archiveName, tempFile, stagingDir)
+	} else if isZip(info.archiveName) {
+		err = updater.extractZipTo(tempFile, stagingDir)
+	} else {
+		err = unsupportedArchive(info.archiveName)
+	}
+	span.End(err)
+	if err != nil {
+		staged.remove()
+		return nil, err
+	}
+
+	staged.binaryPath, err = findFile(stagingDir, info.binaryName)
+	if err == nil && staged.binaryPath == "" {
+		err = fmt.Errorf("Error extracting binary from %s. %w %s", info.archiveName, ErrBinaryNotFoundInArchive, info.binaryName)
+	}
+	if err == nil {
+		var stat os.FileInfo
+		stat, err = os.Stat(staged.binaryPath)
+		if err == nil {
+			err = os.Chmod(staged.binaryPath, updater.binaryMode(stat.Mode()))
+		}
+	}
+	if err == nil && info.migrationName != "" {
+		staged.migrationPath, err = findFile(stagingDir, info.migrationName)
+		if err == nil && staged.migrationPath == "" {
+			err = fmt.Errorf("Error extracting migration from %s. No file matched the name %s", info.archiveName, info.migrationName)
+		}
+	}
+	if err == nil && updater.config.ArchiveChecksumFile != "" {
+		err = updater.verifyStagedChecksum(staged, info.binaryName)
+	}
+	if err != nil {
+		staged.remove()
+		return nil, err
+	}
+
+	updater.logger().Debug("Extracted archive", "archive", info.archiveName, "dir", stagingDir)
+	return staged, nil
+}
+
+func (updater *Updater) verifyStagedChecksum(staged *stagedUpdate, binaryName string) error {
+	ex := &extraction{binaryPath: staged.binaryPath}
+
+	checksumPath, err := findFile(staged.path, updater.config.ArchiveChecksumFile)
+	if err != nil {
+		return err
+	}
+	if checksumPath != "" {
+		ex.checksums, err = os.ReadFile(checksumPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return verifyArchiveChecksum(ex, updater.config.ArchiveChecksumFile, binaryName)
+}
+
+func findFile(root string, name string) (string, error) {
+	found := ""
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && entry.Name() == name {
+			found = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+
+	return found, err
+}
+
+// installDir swaps the staged directory with the install directory. The
+// previous install directory is restored if the staged directory cannot be
+// moved into place.
+func (updater *Updater) installDir(staged *stagedUpdate) (*installation, error) {
+	installDir := filepath.Clean(updater.config.InstallDir)
+	backupDir := installDir + ".old-" + uuid.NewString()
+
+	relativeBinaryPath, err := filepath.Rel(staged.path, staged.binaryPath)
+	if err != nil {
+		staged.remove()
+		return nil, err
+	}
+	installed := &installation{
+		target:     installDir,
+		binaryPath: filepath.Join(installDir, relativeBinaryPath),
+		dir:        true,
+	}
+	if staged.migrationPath != "" {
+		relativeMigrationPath, err := filepath.Rel(staged.path, staged.migrationPath)
+		if err != nil {
+			staged.remove()
+			return nil, err
+		}
+		installed.migrationPath = filepath.Join(installDir, relativeMigrationPath)
+	}
+
+	_, err = os.Stat(installDir)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		staged.remove()
+		return nil, err
+	}
+
+	if exists {
+		err = os.Rename(installDir, backupDir)
+		if err != nil {
+			staged.remove()
+			return nil, err
+		}
+		installed.backupPath = backupDir
+		updater.logger().Debug("Moved the install directory aside", "dir", installDir, "backup", backupDir)
+	}
+
+	err = os.Rename(staged.path, installDir)
+	if err != nil {
+		if exists {
+			restoreErr := os.Rename(backupDir, installDir)
+			if restoreErr != nil {
+				return nil, fmt.Errorf("Failed to install %s and failed to restore the previous install from %s. %w", installDir, backupDir, errors.Join(err, restoreErr))
+			}
+		}
+		staged.remove()
+		return nil, err
+	}
+
+	updater.logger().Debug("Installed directory", "dir", installDir)
+	return installed, nil
+}
@@ -0,0 +1,104 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	cidCodecRaw     = 0x55
+	multihashSha256 = 0x12
+)
+
+// parseRawCid decodes a CIDv1 using the raw codec and a sha2-256 multihash,
+// returning the digest. Other CIDs address DAG nodes rather than the file
+// content, so the downloaded bytes cannot be verified against them directly.
+func parseRawCid(cid string) ([]byte, error) {
+	if cid == "" {
+		return nil, fmt.Errorf("Empty CID")
+	}
+
+	var data []byte
+	var err error
+	switch cid[0] {
+	case 'b':
+		data, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(cid[1:]))
+	case 'f':
+		data, err = hex.DecodeString(cid[1:])
+	default:
+		return nil, fmt.Errorf("Unsupported CID %s. Only base32 and base16 CIDv1 are supported", cid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CID %s. %w", cid, err)
+	}
+
+	reader := bytes.NewReader(data)
+	fields := make([]uint64, 4)
+	for i := range fields {
+		fields[i], err = binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CID %s. %w", cid, err)
+		}
+	}
+
+	version, codec, hashCode, hashLength := fields[0], fields[1], fields[2], fields[3]
+	if version != 1 || codec != cidCodecRaw || hashCode != multihashSha256 || hashLength != sha256.Size {
+		return nil, fmt.Errorf("Unsupported CID %s. Only CIDv1 raw sha2-256 CIDs can be verified", cid)
+	}
+
+	digest, err := io.ReadAll(reader)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("Invalid CID %s. Truncated multihash", cid)
+	}
+
+	return digest, nil
+}
+
+func (updater *Updater) ipfsGatewayUrl(alternate *url.URL) (string, error) {
+	if updater.config.IpfsGateway == "" {
+		return "", fmt.Errorf("Cannot download %s. IpfsGateway is not configured", alternate)
+	}
+	if strings.Trim(alternate.Path, "/") != "" {
+		return "", fmt.Errorf("Cannot download %s. Only ipfs://<cid> urls without a path are supported", alternate)
+	}
+
+	_, err := parseRawCid(alternate.Host)
+	if err != nil {
+		return "", err
+	}
+
+	return url.JoinPath(updater.config.IpfsGateway, "ipfs", alternate.Host)
+}
+
+func verifyCid(cid string, path string) error {
+	expected, err := parseRawCid(cid)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(hash.Sum(nil), expected) {
+		return fmt.Errorf("%w. Downloaded content does not match CID %s", ErrChecksumMismatch, cid)
+	}
+
+	return nil
+}
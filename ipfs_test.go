@@ -0,0 +1,104 @@
+package updater
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The CIDv1 of "hello world" added with raw leaves, and its sha2-256 digest.
+const (
+	helloWorldCid    = "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"
+	helloWorldDigest = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+)
+
+// base32Cid encodes the hex encoded CID bytes with the multibase base32
+// prefix.
+func base32Cid(t *testing.T, encoded string) string {
+	t.Helper()
+	data, err := hex.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return "b" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(data))
+}
+
+func TestParseRawCid(t *testing.T) {
+	tests := []struct {
+		name    string
+		cid     string
+		want    string
+		wantErr bool
+	}{
+		{name: "base32", cid: helloWorldCid, want: helloWorldDigest},
+		{name: "base16", cid: "f01551220" + helloWorldDigest, want: helloWorldDigest},
+		{name: "base32 from bytes", cid: base32Cid(t, "01551220"+helloWorldDigest), want: helloWorldDigest},
+		{name: "empty", cid: "", wantErr: true},
+		{name: "CIDv0", cid: "QmaozNR7DZHQK1ZcU9p7QdrshMvXqWK6gpu5rmrkPdT3L4", wantErr: true},
+		{name: "unsupported multibase", cid: "z" + helloWorldCid[1:], wantErr: true},
+		{name: "invalid base32", cid: "b!" + helloWorldCid[2:], wantErr: true},
+		{name: "invalid base16", cid: "f0155122g" + helloWorldDigest, wantErr: true},
+		{name: "version 0", cid: "f00551220" + helloWorldDigest, wantErr: true},
+		{name: "dag-pb codec", cid: "f01701220" + helloWorldDigest, wantErr: true},
+		{name: "sha1 multihash", cid: "f01551114" + helloWorldDigest[:40], wantErr: true},
+		{name: "wrong multihash length", cid: "f01551210" + helloWorldDigest[:32], wantErr: true},
+		{name: "truncated digest", cid: "f01551220" + helloWorldDigest[:62], wantErr: true},
+		{name: "extra digest bytes", cid: "f01551220" + helloWorldDigest + "00", wantErr: true},
+		{name: "truncated header", cid: "f0155", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseRawCid(test.cid)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("parseRawCid() = %x, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRawCid() error = %v", err)
+			}
+			if hex.EncodeToString(got) != test.want {
+				t.Errorf("parseRawCid() = %x, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(path, []byte("hello world"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cid     string
+		wantErr error
+	}{
+		{name: "match", cid: helloWorldCid},
+		{name: "mismatch", cid: "f01551220" + strings.Repeat("00", 32), wantErr: ErrChecksumMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyCid(test.cid, path)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("verifyCid() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyCid() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package updater
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwsPayload struct {
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+}
+
+func decodeJwkInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+// ParseJwk parses an OKP (Ed25519), EC (P-256, P-384, P-521) or RSA public
+// JSON Web Key.
+func ParseJwk(data []byte) (crypto.PublicKey, error) {
+	var key jwk
+	err := json.Unmarshal(data, &key)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWK. %w", err)
+	}
+
+	switch key.Kty {
+	case "OKP":
+		if key.Crv != "Ed25519" {
+			return nil, fmt.Errorf("Unsupported JWK curve %s", key.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Invalid Ed25519 JWK")
+		}
+		return ed25519.PublicKey(x), nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported JWK curve %s", key.Crv)
+		}
+		x, err := decodeJwkInt(key.X)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid EC JWK. %w", err)
+		}
+		y, err := decodeJwkInt(key.Y)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid EC JWK. %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "RSA":
+		n, err := decodeJwkInt(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid RSA JWK. %w", err)
+		}
+		e, err := decodeJwkInt(key.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("Invalid RSA JWK")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported JWK key type %s", key.Kty)
+	}
+}
+
+func verifyJwsSignature(key crypto.PublicKey, alg string, signingInput []byte, signature []byte) error {
+	switch alg {
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if ok && ed25519.Verify(edKey, signingInput, signature) {
+			return nil
+		}
+	case "ES256", "ES384", "ES512":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			break
+		}
+		var digest []byte
+		switch alg {
+		case "ES256":
+			sum := sha256.Sum256(signingInput)
+			digest = sum[:]
+		case "ES384":
+			sum := sha512.Sum384(signingInput)
+			digest = sum[:]
+		default:
+			sum := sha512.Sum512(signingInput)
+			digest = sum[:]
+		}
+		size := len(signature) / 2
+		if len(signature)%2 != 0 || size != (ecKey.Curve.Params().BitSize+7)/8 {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(ecKey, digest, r, s) {
+			return nil
+		}
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		digest := sha256.Sum256(signingInput)
+		if rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	default:
+		return fmt.Errorf("Unsupported JWS algorithm %s", alg)
+	}
+
+	return fmt.Errorf("JWS signature verification failed")
+}
+
+func parseJws(token string, key crypto.PublicKey) (*jwsPayload, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Invalid JWS. Expected compact serialization")
+	}
+
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS header. %w", err)
+	}
+	var header jwsHeader
+	err = json.Unmarshal(headerJson, &header)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS header. %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS signature. %w", err)
+	}
+
+	err = verifyJwsSignature(key, header.Alg, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS payload. %w", err)
+	}
+	var payload jwsPayload
+	err = json.Unmarshal(payloadJson, &payload)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JWS payload. %w", err)
+	}
+
+	return &payload, nil
+}
+
+func (updater *Updater) verifyJws(manifest *UpdaterManifest, name string, path string) error {
+	token, ok := manifest.Jws[name]
+	if !ok {
+		return fmt.Errorf("Manifest does not contain a JWS for %s", name)
+	}
+
+	payload, err := parseJws(token, updater.config.JwsKey)
+	if err != nil {
+		return fmt.Errorf("Error verifying JWS for %s. %w", name, err)
+	}
+
+	if payload.Name != "" && payload.Name != name {
+		return fmt.Errorf("JWS was issued for %s but downloaded %s", payload.Name, name)
+	}
+	if payload.Sha256 == "" {
+		return fmt.Errorf("JWS for %s does not contain a sha256 checksum", name)
+	}
+
+	actual, err := fileSha256(path)
+	if err != nil {
+		return err
+	}
+	if actual != strings.ToLower(payload.Sha256) {
+		return fmt.Errorf("%w for %s. Expected %s but got %s", ErrChecksumMismatch, name, payload.Sha256, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package updater
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The Ed25519 JWS example of RFC 8037, appendix A.4.
+const (
+	rfc8037Jwk = `{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	rfc8037Jws = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+)
+
+func TestVerifyJwsSignature(t *testing.T) {
+	key, err := ParseJwk([]byte(rfc8037Jwk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(rfc8037Jws, ".")
+	signingInput := parts[0] + "." + parts[1]
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, signature...)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name         string
+		key          crypto.PublicKey
+		alg          string
+		signingInput string
+		signature    []byte
+		wantErr      bool
+	}{
+		{name: "rfc 8037", key: key, alg: "EdDSA", signingInput: signingInput, signature: signature},
+		{name: "wrong key", key: otherKey, alg: "EdDSA", signingInput: signingInput, signature: signature, wantErr: true},
+		{name: "tampered payload", key: key, alg: "EdDSA", signingInput: signingInput + "x", signature: signature, wantErr: true},
+		{name: "tampered signature", key: key, alg: "EdDSA", signingInput: signingInput, signature: tampered, wantErr: true},
+		{name: "truncated signature", key: key, alg: "EdDSA", signingInput: signingInput, signature: signature[:ed25519.SignatureSize-1], wantErr: true},
+		{name: "empty signature", key: key, alg: "EdDSA", signingInput: signingInput, signature: nil, wantErr: true},
+		{name: "algorithm of another key type", key: key, alg: "ES256", signingInput: signingInput, signature: signature, wantErr: true},
+		{name: "none", key: key, alg: "none", signingInput: signingInput, signature: nil, wantErr: true},
+		{name: "hmac", key: key, alg: "HS256", signingInput: signingInput, signature: signature, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyJwsSignature(test.key, test.alg, []byte(test.signingInput), test.signature)
+			if test.wantErr && err == nil {
+				t.Fatal("verifyJwsSignature() succeeded, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("verifyJwsSignature() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseJws(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := jwsPayload{Name: "app.tar.gz", Sha256: strings.Repeat("ab", sha256.Size)}
+	sign := func(key crypto.Signer) string {
+		token, err := signJws(key, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	edToken := sign(edKey)
+	p256Token := sign(p256)
+	rsaToken := sign(rsaKey)
+
+	tests := []struct {
+		name    string
+		token   string
+		key     crypto.PublicKey
+		wantErr bool
+	}{
+		{name: "EdDSA", token: edToken, key: edKey.Public()},
+		{name: "ES256", token: p256Token, key: p256.Public()},
+		{name: "ES384", token: sign(p384), key: p384.Public()},
+		{name: "ES512", token: sign(p521), key: p521.Public()},
+		{name: "RS256", token: rsaToken, key: rsaKey.Public()},
+		{name: "surrounding whitespace", token: " " + edToken + "\n", key: edKey.Public()},
+		{name: "EdDSA wrong key", token: edToken, key: ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)), wantErr: true},
+		{name: "ES256 wrong curve", token: p256Token, key: p384.Public(), wantErr: true},
+		{name: "ES256 wrong key type", token: p256Token, key: edKey.Public(), wantErr: true},
+		{name: "RS256 wrong key type", token: rsaToken, key: p256.Public(), wantErr: true},
+		{name: "EdDSA truncated signature", token: edToken[:len(edToken)-4], key: edKey.Public(), wantErr: true},
+		{name: "ES256 truncated signature", token: p256Token[:len(p256Token)-4], key: p256.Public(), wantErr: true},
+		{name: "RS256 truncated signature", token: rsaToken[:len(rsaToken)-4], key: rsaKey.Public(), wantErr: true},
+		{name: "tampered payload", token: replacePart(edToken, 1, `{"name":"app.tar.gz","sha256":"00"}`), key: edKey.Public(), wantErr: true},
+		{name: "downgraded algorithm", token: replacePart(edToken, 0, `{"alg":"none"}`), key: edKey.Public(), wantErr: true},
+		{name: "missing signature", token: strings.Join(strings.Split(edToken, ".")[:2], "."), key: edKey.Public(), wantErr: true},
+		{name: "empty", token: "", key: edKey.Public(), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseJws(test.token, test.key)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("parseJws() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJws() error = %v", err)
+			}
+			if *got != payload {
+				t.Errorf("parseJws() = %+v, want %+v", *got, payload)
+			}
+		})
+	}
+}
+
+// replacePart replaces a part of a compact JWS with the encoded value.
+func replacePart(token string, i int, value string) string {
+	parts := strings.Split(token, ".")
+	parts[i] = base64.RawURLEncoding.EncodeToString([]byte(value))
+	return strings.Join(parts, ".")
+}
+
+func TestVerifyJws(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("binary")
+	path := filepath.Join(t.TempDir(), "app.tar.gz")
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	token := func(payload jwsPayload) string {
+		token, err := signJws(private, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		jws     map[string]string
+		wantErr bool
+	}{
+		{name: "valid", jws: map[string]string{"app.tar.gz": token(jwsPayload{Name: "app.tar.gz", Sha256: checksum})}},
+		{name: "uppercase checksum", jws: map[string]string{"app.tar.gz": token(jwsPayload{Name: "app.tar.gz", Sha256: strings.ToUpper(checksum)})}},
+		{name: "missing token", jws: map[string]string{"other.tar.gz": token(jwsPayload{Name: "other.tar.gz", Sha256: checksum})}, wantErr: true},
+		{name: "token of another artifact", jws: map[string]string{"app.tar.gz": token(jwsPayload{Name: "other.tar.gz", Sha256: checksum})}, wantErr: true},
+		{name: "wrong checksum", jws: map[string]string{"app.tar.gz": token(jwsPayload{Name: "app.tar.gz", Sha256: strings.Repeat("0", 64)})}, wantErr: true},
+		{name: "missing checksum", jws: map[string]string{"app.tar.gz": token(jwsPayload{Name: "app.tar.gz"})}, wantErr: true},
+	}
+
+	updater := New(&UpdaterConfig{JwsKey: public})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := updater.verifyJws(&UpdaterManifest{Jws: test.jws}, "app.tar.gz", path)
+			if test.wantErr && err == nil {
+				t.Fatal("verifyJws() succeeded, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("verifyJws() error = %v", err)
+			}
+		})
+	}
+}
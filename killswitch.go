@@ -0,0 +1,43 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+)
+
+// KillSwitch revokes the versions matching a version constraint, e.g.,
+// ">=1.2.0 <1.2.5".
+type KillSwitch struct {
+	Versions string `json:"versions"`
+	Message  string `json:"message"`
+}
+
+// Revoked reports whether the current version is revoked by a kill switch in
+// the manifest, along with the message of the matching kill switch.
+func (updater *Updater) Revoked() (bool, string, error) {
+	return updater.RevokedContext(context.Background())
+}
+
+func (updater *Updater) RevokedContext(ctx context.Context) (bool, string, error) {
+	current, err := parseVersion(updater.config.CurrentVersion)
+	if err != nil {
+		return false, "", fmt.Errorf("Invalid current version. %w", err)
+	}
+
+	manifest, err := updater.GetManifestContext(ctx)
+	if err != nil {
+		return false, "", err
+	}
+
+	for _, killSwitch := range manifest.KillSwitch {
+		constraint, err := parseConstraint(killSwitch.Versions)
+		if err != nil {
+			return false, "", err
+		}
+		if constraint.matches(current) {
+			return true, killSwitch.Message, nil
+		}
+	}
+
+	return false, "", nil
+}
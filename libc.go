@@ -0,0 +1,32 @@
+package updater
+
+import (
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	LibcGlibc = "glibc"
+	LibcMusl  = "musl"
+)
+
+// detectLibc reports the C library of the host by looking for the dynamic
+// loader. It returns the empty string on platforms other than Linux or when
+// no loader is found, e.g., in scratch containers.
+func detectLibc() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		return LibcMusl
+	}
+
+	for _, pattern := range []string{"/lib*/ld-linux*.so.*", "/lib/*-linux-gnu*/ld-linux*.so.*"} {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return LibcGlibc
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,82 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrDownloadTooLarge = errors.New("Download exceeds the maximum size")
+
+// checkDownloadSize fails when size, or -1 when unknown, exceeds
+// MaxDownloadBytes.
+func (updater *Updater) checkDownloadSize(name string, size int64) error {
+	max := updater.config.MaxDownloadBytes
+	if max > 0 && size > max {
+		return fmt.Errorf("%w. %s is %d bytes but downloads are limited to %d bytes", ErrDownloadTooLarge, name, size, max)
+	}
+
+	return nil
+}
+
+// limitDownload limits the download of name to MaxDownloadBytes. size is the
+// size of the whole file, or -1 when unknown, and offset the bytes already
+// downloaded, e.g., when resuming.
+func (updater *Updater) limitDownload(name string, offset int64, size int64, reader io.Reader) (io.Reader, error) {
+	err := updater.checkDownloadSize(name, size)
+	if err != nil || updater.config.MaxDownloadBytes <= 0 {
+		return reader, err
+	}
+
+	max := updater.config.MaxDownloadBytes
+	return &limitedDownload{name: name, reader: reader, remaining: max - offset, max: max}, nil
+}
+
+type limitedDownload struct {
+	name      string
+	reader    io.Reader
+	remaining int64
+	max       int64
+}
+
+func (download *limitedDownload) Read(p []byte) (int, error) {
+	n, err := download.reader.Read(p)
+	download.remaining -= int64(n)
+	if download.remaining < 0 {
+		return n, fmt.Errorf("%w. %s exceeds the limit of %d bytes", ErrDownloadTooLarge, download.name, download.max)
+	}
+
+	return n, err
+}
+
+// maxSidecarBytes limits the files read into memory, e.g., signatures,
+// checksums and release metadata, when MaxDownloadBytes is not set or larger.
+const maxSidecarBytes = 32 << 20
+
+// readSidecar reads a file downloaded into memory, limited to
+// maxSidecarBytes and MaxDownloadBytes. size is the size of the file, or -1
+// when unknown.
+func (updater *Updater) readSidecar(name string, size int64, reader io.Reader) ([]byte, error) {
+	max := int64(maxSidecarBytes)
+	if updater.config.MaxDownloadBytes > 0 {
+		max = min(max, updater.config.MaxDownloadBytes)
+	}
+
+	return readLimited(name, size, reader, max)
+}
+
+func readLimited(name string, size int64, reader io.Reader, max int64) ([]byte, error) {
+	if size > max {
+		return nil, fmt.Errorf("%w. %s is %d bytes but is limited to %d bytes", ErrDownloadTooLarge, name, size, max)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("%w. %s exceeds the limit of %d bytes", ErrDownloadTooLarge, name, max)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,61 @@
+package updater
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrLinkPolicyViolation = errors.New("Binary violates the link policy")
+
+type LinkPolicy struct {
+	Static           bool
+	AllowedLibraries []string
+}
+
+func (policy *LinkPolicy) check(path string) error {
+	file, err := elf.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error reading ELF binary. %w", err)
+	}
+	defer file.Close()
+
+	interpreter := ""
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data, err := io.ReadAll(prog.Open())
+		if err != nil {
+			return fmt.Errorf("Error reading ELF interpreter. %w", err)
+		}
+		interpreter = strings.TrimRight(string(data), "\x00")
+	}
+
+	libraries, err := file.ImportedLibraries()
+	if err != nil {
+		return fmt.Errorf("Error reading ELF dynamic section. %w", err)
+	}
+
+	if policy.Static && (interpreter != "" || len(libraries) > 0) {
+		return fmt.Errorf("%w. Expected a statically linked binary but got interpreter %q and libraries %v", ErrLinkPolicyViolation, interpreter, libraries)
+	}
+
+	if len(policy.AllowedLibraries) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(policy.AllowedLibraries))
+	for _, library := range policy.AllowedLibraries {
+		allowed[library] = true
+	}
+	for _, library := range libraries {
+		if !allowed[library] {
+			return fmt.Errorf("%w. Binary links against %s which is not an allowed library", ErrLinkPolicyViolation, library)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package updater
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+)
+
+// discardHandler drops every record, logging is off without a Logger.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (handler discardHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return handler
+}
+func (handler discardHandler) WithGroup(string) slog.Handler {
+	return handler
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+func (updater *Updater) logger() *slog.Logger {
+	if updater.config.Logger != nil {
+		return updater.config.Logger
+	}
+
+	return discardLogger
+}
+
+// redactUrl leaves the credentials and the query, e.g., a presigned token, out
+// of logged urls.
+func redactUrl(requestUrl *url.URL) string {
+	redacted := *requestUrl
+	redacted.User = nil
+	redacted.RawQuery = ""
+
+	return redacted.String()
+}
+
+func redactUrlString(requestUrl string) string {
+	parsed, err := url.Parse(requestUrl)
+	if err != nil {
+		return ""
+	}
+
+	return redactUrl(parsed)
+}
+
+// progressLogSteps is the number of progress records logged per download of
+// a known size.
+const progressLogSteps = 10
+
+// progressLogBytes is the interval in bytes of the progress records logged
+// for downloads of an unknown size.
+const progressLogBytes = 10 << 20
+
+// logTo logs the progress at debug level, every tenth of the download or
+// every 10 MiB.
+func (reader *progressReader) logTo(logger *slog.Logger) {
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	reader.logger = logger
+	reader.nextLog = reader.done + reader.logInterval()
+}
+
+func (reader *progressReader) logInterval() int64 {
+	if reader.total > 0 {
+		return max(reader.total/progressLogSteps, 1)
+	}
+
+	return progressLogBytes
+}
+
+func (reader *progressReader) logProgress() {
+	if reader.logger == nil || reader.done < reader.nextLog {
+		return
+	}
+
+	reader.logger.Debug("Download progress", "name", reader.name, "bytes", reader.done, "total", reader.total)
+	for reader.nextLog <= reader.done {
+		reader.nextLog += reader.logInterval()
+	}
+}
@@ -0,0 +1,22 @@
+package updater
+
+import (
+	"os/exec"
+	"regexp"
+)
+
+var platformUuidPattern = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+func readMachineId() string {
+	output, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return ""
+	}
+
+	match := platformUuidPattern.FindSubmatch(output)
+	if match == nil {
+		return ""
+	}
+
+	return string(match[1])
+}
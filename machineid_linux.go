@@ -0,0 +1,17 @@
+package updater
+
+import (
+	"os"
+	"strings"
+)
+
+func readMachineId() string {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.TrimSpace(string(data)) != "" {
+			return strings.TrimSpace(string(data))
+		}
+	}
+
+	return ""
+}
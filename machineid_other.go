@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package updater
+
+func readMachineId() string {
+	return ""
+}
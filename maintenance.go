@@ -0,0 +1,92 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrOutsideMaintenanceWindow = errors.New("Outside of the maintenance window")
+
+type MaintenanceWindow struct {
+	Start    string
+	End      string
+	Location *time.Location
+	Days     []time.Weekday
+}
+
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid maintenance window time %q. Expected HH:MM. %w", value, err)
+	}
+
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func (window *MaintenanceWindow) allowsDay(day time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+
+	for _, allowed := range window.Days {
+		if allowed == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Contains reports whether t falls within the window. Windows that end before
+// they start wrap past midnight, in which case Days refers to the day the
+// window opened.
+func (window *MaintenanceWindow) Contains(t time.Time) (bool, error) {
+	start, err := parseClock(window.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(window.End)
+	if err != nil {
+		return false, err
+	}
+
+	location := window.Location
+	if location == nil {
+		location = time.Local
+	}
+	t = t.In(location)
+	// The wall clock, rather than the time elapsed since midnight, which is
+	// off by the offset change on days with a daylight saving transition.
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	if start < end {
+		return clock >= start && clock < end && window.allowsDay(t.Weekday()), nil
+	}
+
+	if clock >= start {
+		return window.allowsDay(t.Weekday()), nil
+	}
+	if clock < end {
+		return window.allowsDay((t.Weekday() + 6) % 7), nil
+	}
+
+	return false, nil
+}
+
+func (updater *Updater) checkMaintenanceWindow() error {
+	window := updater.config.MaintenanceWindow
+	if window == nil {
+		return nil
+	}
+
+	open, err := window.Contains(time.Now())
+	if err != nil {
+		return err
+	}
+	if !open {
+		return fmt.Errorf("%w. Updates can only be installed between %s and %s", ErrOutsideMaintenanceWindow, window.Start, window.End)
+	}
+
+	return nil
+}
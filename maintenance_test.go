@@ -0,0 +1,96 @@
+package updater
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		window MaintenanceWindow
+		time   time.Time
+		want   bool
+	}{
+		{
+			name:   "inside",
+			window: MaintenanceWindow{Start: "02:00", End: "04:00", Location: time.UTC},
+			time:   time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "at the end",
+			window: MaintenanceWindow{Start: "02:00", End: "04:00", Location: time.UTC},
+			time:   time.Date(2024, 6, 1, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "converted to the location",
+			window: MaintenanceWindow{Start: "02:00", End: "04:00", Location: newYork},
+			time:   time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "spring forward",
+			window: MaintenanceWindow{Start: "03:30", End: "05:00", Location: newYork},
+			time:   time.Date(2024, 3, 10, 4, 0, 0, 0, newYork),
+			want:   true,
+		},
+		{
+			name:   "spring forward before the window",
+			window: MaintenanceWindow{Start: "04:30", End: "05:00", Location: newYork},
+			time:   time.Date(2024, 3, 10, 4, 0, 0, 0, newYork),
+		},
+		{
+			name:   "fall back",
+			window: MaintenanceWindow{Start: "02:00", End: "03:30", Location: newYork},
+			time:   time.Date(2024, 11, 3, 3, 0, 0, 0, newYork),
+			want:   true,
+		},
+		{
+			name:   "fall back after the window",
+			window: MaintenanceWindow{Start: "02:00", End: "02:30", Location: newYork},
+			time:   time.Date(2024, 11, 3, 3, 0, 0, 0, newYork),
+		},
+		{
+			name:   "wraps past midnight",
+			window: MaintenanceWindow{Start: "22:00", End: "02:00", Location: time.UTC, Days: []time.Weekday{time.Saturday}},
+			time:   time.Date(2024, 6, 2, 1, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "wraps past midnight on another day",
+			window: MaintenanceWindow{Start: "22:00", End: "02:00", Location: time.UTC, Days: []time.Weekday{time.Sunday}},
+			time:   time.Date(2024, 6, 2, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "wraps past midnight before midnight",
+			window: MaintenanceWindow{Start: "22:00", End: "02:00", Location: time.UTC, Days: []time.Weekday{time.Saturday}},
+			time:   time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "wraps past midnight into monday",
+			window: MaintenanceWindow{Start: "22:00", End: "02:00", Location: time.UTC, Days: []time.Weekday{time.Sunday}},
+			time:   time.Date(2024, 6, 3, 1, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.window.Contains(test.time)
+			if err != nil {
+				t.Fatalf("Contains() error = %v", err)
+			}
+			if got != test.want {
+				t.Fatalf("Contains() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
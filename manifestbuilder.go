@@ -0,0 +1,238 @@
+package updater
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// NewManifest returns a manifest of the current schema for release tooling
+// building manifests in code. binary is the template of the binary name, see
+// the manifest binary.
+//
+//	manifest := updater.NewManifest("1.2.0", "myapp{{.Ext}}")
+//	manifest.Archive = "myapp_{{.Os}}_{{.Arch}}{{.ArchiveExt}}"
+//	err := manifest.AddPlatform("linux", "amd64", "linux", "x86_64")
+//	err = manifest.AddAsset("myapp_linux_x86_64.tar.gz", archive)
+//	err = manifest.Sign(privateKey)
+//	data, err := manifest.Marshal()
+func NewManifest(version string, binary string) *UpdaterManifest {
+	return &UpdaterManifest{
+		SchemaVersion: ManifestSchemaVersion,
+		Version:       version,
+		Binary:        binary,
+		Os:            map[string]string{},
+		Arch:          map[string]map[string]string{},
+	}
+}
+
+// AddPlatform adds a platform to the os and arch maps. goos is the
+// runtime.GOOS and goarch an arch map key, e.g., amd64, amd64-musl, amd64-v3
+// or amd64-v3-musl. os and arch are the names used by the archive/binary names.
+func (manifest *UpdaterManifest) AddPlatform(goos string, goarch string, os string, arch string) error {
+	if goos == "" || goarch == "" || os == "" || arch == "" {
+		return fmt.Errorf("%w. Platforms require the goos, goarch, os and arch", ErrManifestInvalid)
+	}
+	if existing, ok := manifest.Os[goos]; ok && existing != os {
+		return fmt.Errorf("%w. %s is already named %s, not %s", ErrManifestInvalid, goos, existing, os)
+	}
+
+	if manifest.Os == nil {
+		manifest.Os = map[string]string{}
+	}
+	if manifest.Arch == nil {
+		manifest.Arch = map[string]map[string]string{}
+	}
+	if manifest.Arch[os] == nil {
+		manifest.Arch[os] = map[string]string{}
+	}
+	manifest.Os[goos] = os
+	manifest.Arch[os][goarch] = arch
+
+	return nil
+}
+
+// AddAsset adds the SHA-256 checksum and the size of the named
+// archive/binary, read from r, to the manifest checksums and sizes.
+func (manifest *UpdaterManifest) AddAsset(name string, r io.Reader) error {
+	hash := sha256.New()
+	size, err := io.Copy(hash, r)
+	if err != nil {
+		return err
+	}
+
+	if manifest.Checksums == nil {
+		manifest.Checksums = map[string]string{}
+	}
+	if manifest.Sizes == nil {
+		manifest.Sizes = map[string]int64{}
+	}
+	manifest.Checksums[name] = hex.EncodeToString(hash.Sum(nil))
+	manifest.Sizes[name] = size
+
+	return nil
+}
+
+// Sign adds a JWS token for every asset in the manifest checksums to the
+// manifest jws, verified by clients configured with the public key as the
+// JwsKey. Ed25519 (EdDSA), ECDSA P-256, P-384 and P-521 (ES256, ES384 and
+// ES512) and RSA (RS256) keys are supported.
+func (manifest *UpdaterManifest) Sign(key crypto.Signer) error {
+	if len(manifest.Checksums) == 0 {
+		return fmt.Errorf("%w. Nothing to sign, add assets first", ErrManifestInvalid)
+	}
+
+	names := make([]string, 0, len(manifest.Checksums))
+	for name := range manifest.Checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tokens := make(map[string]string, len(names))
+	for _, name := range names {
+		token, err := signJws(key, jwsPayload{Name: name, Sha256: manifest.Checksums[name]})
+		if err != nil {
+			return err
+		}
+		tokens[name] = token
+	}
+
+	if manifest.Jws == nil {
+		manifest.Jws = map[string]string{}
+	}
+	for name, token := range tokens {
+		manifest.Jws[name] = token
+	}
+
+	return nil
+}
+
+// signJws returns the compact JWS of the payload, the counterpart of
+// parseJws.
+func signJws(key crypto.Signer, payload jwsPayload) (string, error) {
+	var alg string
+	var hash crypto.Hash
+	switch public := key.Public().(type) {
+	case ed25519.PublicKey:
+		alg = "EdDSA"
+	case *ecdsa.PublicKey:
+		switch public.Curve.Params().BitSize {
+		case 256:
+			alg, hash = "ES256", crypto.SHA256
+		case 384:
+			alg, hash = "ES384", crypto.SHA384
+		case 521:
+			alg, hash = "ES512", crypto.SHA512
+		default:
+			return "", fmt.Errorf("Unsupported ECDSA curve %s", public.Curve.Params().Name)
+		}
+	case *rsa.PublicKey:
+		alg, hash = "RS256", crypto.SHA256
+	default:
+		return "", fmt.Errorf("Unsupported signing key %T", public)
+	}
+
+	header, err := json.Marshal(jwsHeader{Alg: alg})
+	if err != nil {
+		return "", err
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payloadJson)
+
+	digest := []byte(signingInput)
+	switch hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(digest)
+		digest = sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(digest)
+		digest = sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(digest)
+		digest = sum[:]
+	}
+
+	signature, err := key.Sign(rand.Reader, digest, hash)
+	if err != nil {
+		return "", err
+	}
+
+	// JWS ECDSA signatures are the fixed size r and s, not ASN.1.
+	if public, ok := key.Public().(*ecdsa.PublicKey); ok {
+		var parsed struct {
+			R, S *big.Int
+		}
+		_, err = asn1.Unmarshal(signature, &parsed)
+		if err != nil {
+			return "", err
+		}
+		size := (public.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		parsed.R.FillBytes(signature[:size])
+		parsed.S.FillBytes(signature[size:])
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Marshal validates the manifest and returns it as indented JSON, as read by
+// ParseManifest and clients.
+func (manifest *UpdaterManifest) Marshal() ([]byte, error) {
+	err := manifest.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// MarshalJSON leaves the build and publish times out when they are not set.
+func (manifest UpdaterManifest) MarshalJSON() ([]byte, error) {
+	type plain UpdaterManifest
+	return json.Marshal(struct {
+		plain
+		BuildTime   *time.Time `json:"buildTime,omitempty"`
+		PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	}{
+		plain:       plain(manifest),
+		BuildTime:   optionalTime(manifest.BuildTime),
+		PublishedAt: optionalTime(manifest.PublishedAt),
+	})
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// ParseManifest parses a manifest like clients do, e.g., to add a release to
+// a published manifest. The format is selected by the extension of name,
+// JSON by default, see UpdaterConfig. Older schema versions are migrated and
+// the manifest is validated.
+func ParseManifest(data []byte, name string) (*UpdaterManifest, error) {
+	return parseManifest(data, manifestFormat(name, ""))
+}
@@ -0,0 +1,160 @@
+package updater
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// The manifest cache keeps the last manifest fetched from the BaseUrl next to
+// the StorePath, along with its ETag and Last-Modified date, so that the
+// manifest is only downloaded again when it changed. The body is kept as
+// fetched for signatures to verify.
+const manifestCacheFile = "updater-manifest.json"
+
+type manifestCache struct {
+	Url          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+// verifiedManifest is the manifest last parsed and verified by this Updater,
+// reused as is when the manifest did not change.
+type verifiedManifest struct {
+	body     []byte
+	manifest *UpdaterManifest
+}
+
+func (updater *Updater) manifestCachePath() (string, error) {
+	path, err := updater.storePath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), manifestCacheFile), nil
+}
+
+// readManifestCache returns the cached manifest fetched from manifestUrl, or
+// nil when there is none.
+func (updater *Updater) readManifestCache(manifestUrl string) *manifestCache {
+	if !updater.config.CacheManifest {
+		return nil
+	}
+
+	path, err := updater.manifestCachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var cached manifestCache
+	err = json.Unmarshal(data, &cached)
+	if err != nil || cached.Url != manifestUrl || (cached.ETag == "" && cached.LastModified == "") {
+		return nil
+	}
+
+	return &cached
+}
+
+// conditionalHeader returns the headers making the request for the manifest
+// conditional on it having changed since it was cached.
+func (cached *manifestCache) conditionalHeader() http.Header {
+	header := http.Header{}
+	if cached == nil {
+		return header
+	}
+
+	if cached.ETag != "" {
+		header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	return header
+}
+
+// cacheManifest stores the manifest fetched from manifestUrl. Caching is best
+// effort, failures only mean the manifest is downloaded again.
+func (updater *Updater) cacheManifest(manifestUrl string, resp *http.Response, body []byte) {
+	if !updater.config.CacheManifest {
+		return
+	}
+
+	cached := manifestCache{
+		Url:          manifestUrl,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         body,
+	}
+	if cached.ETag == "" && cached.LastModified == "" {
+		path, err := updater.manifestCachePath()
+		if err == nil {
+			os.Remove(path)
+		}
+		return
+	}
+
+	_ = updater.writeManifestCache(&cached)
+}
+
+func (updater *Updater) writeManifestCache(cached *manifestCache) error {
+	path, err := updater.manifestCachePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tempPath := path + "." + uuid.NewString()
+	err = os.WriteFile(tempPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempPath, path)
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
+
+// lastManifest returns a copy of the manifest last verified by this Updater
+// when its body is body.
+func (updater *Updater) lastManifest(body []byte) *UpdaterManifest {
+	updater.stateMu.Lock()
+	defer updater.stateMu.Unlock()
+
+	if updater.verified == nil || string(updater.verified.body) != string(body) {
+		return nil
+	}
+
+	manifest := *updater.verified.manifest
+	return &manifest
+}
+
+func (updater *Updater) setLastManifest(body []byte, manifest *UpdaterManifest) {
+	if !updater.config.CacheManifest {
+		return
+	}
+
+	copied := *manifest
+	updater.stateMu.Lock()
+	updater.verified = &verifiedManifest{body: body, manifest: &copied}
+	updater.stateMu.Unlock()
+}
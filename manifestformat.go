@@ -0,0 +1,153 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifests are JSON, YAML or TOML. YAML and TOML manifests are converted to
+// JSON before they are parsed, so that all formats share the JSON field names.
+// Signatures and checksums cover the manifest as fetched.
+const (
+	manifestJson = "json"
+	manifestYaml = "yaml"
+	manifestToml = "toml"
+)
+
+// manifestFormat selects the format of the manifest by the extension of its
+// name, or else by the Content-Type it was served with, defaulting to JSON.
+func manifestFormat(name string, contentType string) string {
+	if parsed, err := url.Parse(name); err == nil {
+		name = parsed.Path
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return manifestJson
+	case ".yaml", ".yml":
+		return manifestYaml
+	case ".toml":
+		return manifestToml
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return manifestYaml
+	case "application/toml", "text/toml":
+		return manifestToml
+	}
+
+	return manifestJson
+}
+
+func parseManifest(body []byte, format string) (*UpdaterManifest, error) {
+	var err error
+	data := body
+	switch format {
+	case manifestYaml:
+		data, err = yamlToJson(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w. Invalid YAML. %w", ErrManifestInvalid, err)
+		}
+	case manifestToml:
+		data, err = tomlToJson(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w. Invalid TOML. %w", ErrManifestInvalid, err)
+		}
+	}
+
+	return decodeManifest(data)
+}
+
+func tomlToJson(body []byte) ([]byte, error) {
+	var document map[string]any
+	_, err := toml.Decode(string(body), &document)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+func yamlToJson(body []byte) ([]byte, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(body, &document)
+	if err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return []byte("{}"), nil
+	}
+
+	value, err := yamlValue(document.Content[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// yamlValue converts a YAML node to values encoding/json can marshal. Keys
+// are kept as written, e.g., a 1.10 key stays "1.10". Scalars are typed by
+// YAML, versions such as 1.10 must be quoted to be read as strings.
+func yamlValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		mapping := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind == yaml.AliasNode {
+				key = key.Alias
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("Line %d: keys must be scalars", key.Line)
+			}
+
+			value, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			if key.ShortTag() == "!!merge" {
+				merged, ok := value.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("Line %d: only mappings can be merged", key.Line)
+				}
+				for mergedKey, mergedValue := range merged {
+					if _, exists := mapping[mergedKey]; !exists {
+						mapping[mergedKey] = mergedValue
+					}
+				}
+				continue
+			}
+			mapping[key.Value] = value
+		}
+		return mapping, nil
+	case yaml.SequenceNode:
+		sequence := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, value)
+		}
+		return sequence, nil
+	default:
+		var value any
+		err := node.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
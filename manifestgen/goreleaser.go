@@ -0,0 +1,117 @@
+package manifestgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Artifact is an entry of the artifacts.json GoReleaser writes to its dist
+// directory.
+type Artifact struct {
+	Name    string        `json:"name"`
+	Path    string        `json:"path"`
+	Goos    string        `json:"goos,omitempty"`
+	Goarch  string        `json:"goarch,omitempty"`
+	Goamd64 string        `json:"goamd64,omitempty"`
+	Goarm   string        `json:"goarm,omitempty"`
+	Type    string        `json:"type"`
+	Extra   ArtifactExtra `json:"extra,omitempty"`
+	// InternalType distinguishes the kinds of artifacts sharing a Type, 2
+	// being binaries uploaded as is with archives format binary.
+	InternalType int `json:"internal_type,omitempty"`
+}
+
+type ArtifactExtra struct {
+	// Binaries are the names of the binaries within an archive.
+	Binaries []string `json:"Binaries,omitempty"`
+	// Checksum is the checksum of the artifact prefixed with the algorithm,
+	// e.g., sha256:<hex>.
+	Checksum string `json:"Checksum,omitempty"`
+	// Format is the archive format, e.g., tar.gz, zip or binary.
+	Format string `json:"Format,omitempty"`
+	ID     string `json:"ID,omitempty"`
+}
+
+// Metadata is the metadata.json GoReleaser writes to its dist directory.
+type Metadata struct {
+	ProjectName string    `json:"project_name"`
+	Tag         string    `json:"tag"`
+	Version     string    `json:"version"`
+	Date        time.Time `json:"date"`
+}
+
+// ReadDist reads the artifacts and metadata of a GoReleaser dist directory,
+// or of the directory of an artifacts.json. A missing metadata.json is
+// returned as the zero Metadata.
+func ReadDist(path string) ([]Artifact, Metadata, error) {
+	var metadata Metadata
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, metadata, err
+	}
+	artifactsPath := path
+	if info.IsDir() {
+		artifactsPath = filepath.Join(path, "artifacts.json")
+	}
+
+	data, err := os.ReadFile(artifactsPath)
+	if err != nil {
+		return nil, metadata, err
+	}
+	var artifacts []Artifact
+	err = json.Unmarshal(data, &artifacts)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("Invalid %s. %w", artifactsPath, err)
+	}
+
+	metadataPath := filepath.Join(filepath.Dir(artifactsPath), "metadata.json")
+	data, err = os.ReadFile(metadataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return artifacts, metadata, nil
+	}
+	if err != nil {
+		return nil, metadata, err
+	}
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("Invalid %s. %w", metadataPath, err)
+	}
+
+	return artifacts, metadata, nil
+}
+
+// resolve returns the path of the artifact file. Artifact paths are relative
+// to the directory GoReleaser ran in, the parent of the dist directory.
+func (artifact *Artifact) resolve(dist string) string {
+	if filepath.IsAbs(artifact.Path) {
+		return artifact.Path
+	}
+
+	candidates := []string{
+		filepath.Join(filepath.Dir(dist), artifact.Path),
+		artifact.Path,
+		filepath.Join(dist, artifact.Name),
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// isUploadable reports whether the artifact is published for users to
+// download, an archive, or a binary of archives format binary.
+func (artifact *Artifact) isUploadable(archives bool) bool {
+	if archives {
+		return artifact.Type == "Archive" && artifact.Extra.Format != "binary"
+	}
+
+	return artifact.Type == "Binary" && (artifact.InternalType == 2 || artifact.Name != filepath.Base(artifact.Path))
+}
@@ -0,0 +1,438 @@
+// Package manifestgen generates updater manifests from the dist directory of
+// a GoReleaser release, so that publishers do not write manifests by hand.
+//
+//	manifest, err := manifestgen.Generate("dist", manifestgen.Options{})
+//
+// The archive and binary templates, os and arch maps, archive extensions,
+// checksums and sizes are taken from the artifacts GoReleaser published.
+package manifestgen
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dworthen/updater"
+)
+
+type Options struct {
+	// Version is the version of the release, defaulting to the version in
+	// metadata.json.
+	Version string
+	// ID selects the artifacts of a GoReleaser archives id, or builds id for
+	// releases of binaries, when a release publishes several.
+	ID string
+	// Binary is the name of the binary within archives, a template like the
+	// manifest binary. Defaults to the binary GoReleaser archived, with
+	// {{.Ext}} in place of .exe.
+	Binary string
+	// Product is written to the manifest product, see
+	// updater.UpdaterConfig.ExpectedProduct.
+	Product string
+}
+
+// Generate generates the manifest of the release in the GoReleaser dist
+// directory, or of the directory of an artifacts.json. The manifest is
+// validated before it is returned.
+//
+// Archives are preferred over binaries published with archives format binary.
+// Archive and binary names are templated with {{.Os}}, {{.Arch}} and
+// {{.Version}} when the os, arch and version can be found in every name.
+// Otherwise the arch map lists the name of each platform.
+func Generate(dist string, options Options) (*updater.UpdaterManifest, error) {
+	artifacts, metadata, err := ReadDist(dist)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dist)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		dist = filepath.Dir(dist)
+	}
+
+	if options.Version == "" {
+		options.Version = metadata.Version
+	}
+	if options.Version == "" {
+		return nil, errors.New("Missing version. metadata.json does not list the version, set the version of the release")
+	}
+
+	platforms, archives, err := selectPlatforms(artifacts, options.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &updater.UpdaterManifest{
+		SchemaVersion: updater.ManifestSchemaVersion,
+		Version:       options.Version,
+		Product:       options.Product,
+		BuildTime:     metadata.Date,
+		PublishedAt:   metadata.Date,
+	}
+	if archives {
+		manifest.Binary, err = binaryName(platforms, options.Binary, metadata.ProjectName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !templateNames(manifest, platforms, archives) {
+		literalNames(manifest, platforms, archives)
+	}
+
+	err = addChecksums(manifest, platforms, dist)
+	if err != nil {
+		return nil, err
+	}
+
+	err = manifest.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// platform is the artifact published for a runtime.GOOS and arch map key.
+type platform struct {
+	goos     string
+	key      string
+	artifact *Artifact
+	// stem is the name without the archive extension, or .exe.
+	stem string
+	// ext is the archive extension, or .exe.
+	ext string
+}
+
+// selectPlatforms selects the archive, or else binary, of every platform,
+// reporting whether archives were selected.
+func selectPlatforms(artifacts []Artifact, id string) ([]*platform, bool, error) {
+	archives := true
+	selected := selectArtifacts(artifacts, id, archives)
+	if len(selected) == 0 {
+		archives = false
+		selected = selectArtifacts(artifacts, id, archives)
+	}
+	if len(selected) == 0 {
+		return nil, false, errors.New("No archives or binaries found in the GoReleaser artifacts")
+	}
+
+	byKey := map[string]*platform{}
+	var universal []*platform
+	for _, artifact := range selected {
+		p := &platform{
+			goos:     artifact.Goos,
+			key:      artifact.Goarch,
+			artifact: artifact,
+			stem:     artifact.Name,
+		}
+		if artifact.Goarch == "amd64" && artifact.Goamd64 != "" && artifact.Goamd64 != "v1" {
+			p.key += "-" + artifact.Goamd64
+		}
+		switch {
+		case archives && strings.HasSuffix(artifact.Name, "."+artifact.Extra.Format):
+			p.ext = "." + artifact.Extra.Format
+		case !archives && artifact.Goos == "windows" && strings.HasSuffix(artifact.Name, ".exe"):
+			p.ext = ".exe"
+		}
+		p.stem = strings.TrimSuffix(artifact.Name, p.ext)
+
+		if artifact.Goarch == "all" {
+			universal = append(universal, p)
+			continue
+		}
+
+		existing, ok := byKey[p.goos+"/"+p.key]
+		switch {
+		case !ok:
+			byKey[p.goos+"/"+p.key] = p
+		case artifact.Goarch == "arm" && artifact.Goarm < existing.artifact.Goarm:
+			// runtime.GOARCH does not tell arm versions apart, the
+			// lowest runs everywhere.
+			byKey[p.goos+"/"+p.key] = p
+		case artifact.Goarch != "arm":
+			return nil, false, fmt.Errorf("Several artifacts for %s/%s, %s and %s. Select one by GoReleaser id", p.goos, p.key, existing.artifact.Name, artifact.Name)
+		}
+	}
+
+	// Universal binaries replace the darwin binaries they were made of.
+	for _, p := range universal {
+		for _, goarch := range []string{"amd64", "arm64"} {
+			if _, ok := byKey[p.goos+"/"+goarch]; !ok {
+				byKey[p.goos+"/"+goarch] = &platform{goos: p.goos, key: goarch, artifact: p.artifact, stem: p.stem, ext: p.ext}
+			}
+		}
+	}
+
+	platforms := make([]*platform, 0, len(byKey))
+	for _, p := range byKey {
+		platforms = append(platforms, p)
+	}
+	sort.Slice(platforms, func(i, j int) bool {
+		if platforms[i].goos != platforms[j].goos {
+			return platforms[i].goos < platforms[j].goos
+		}
+		return platforms[i].key < platforms[j].key
+	})
+
+	return platforms, archives, nil
+}
+
+func selectArtifacts(artifacts []Artifact, id string, archives bool) []*Artifact {
+	var selected []*Artifact
+	for i := range artifacts {
+		artifact := &artifacts[i]
+		if artifact.Goos == "" || !artifact.isUploadable(archives) {
+			continue
+		}
+		if id != "" && artifact.Extra.ID != id {
+			continue
+		}
+		selected = append(selected, artifact)
+	}
+
+	return selected
+}
+
+// binaryName returns the name of the binary within the archives.
+func binaryName(platforms []*platform, binary string, projectName string) (string, error) {
+	if binary != "" {
+		return binary, nil
+	}
+
+	for _, p := range platforms {
+		if len(p.artifact.Extra.Binaries) > 0 {
+			return strings.TrimSuffix(path.Base(p.artifact.Extra.Binaries[0]), ".exe") + "{{.Ext}}", nil
+		}
+	}
+	if projectName != "" {
+		return projectName + "{{.Ext}}", nil
+	}
+
+	return "", errors.New("Missing binary name. The artifacts do not list the binaries within archives, set the binary name")
+}
+
+// templateNames templates the archive or binary names with the os, arch and
+// version of each platform, reporting whether every name could be templated
+// and rendered back.
+func templateNames(manifest *updater.UpdaterManifest, platforms []*platform, archives bool) bool {
+	manifest.Os = map[string]string{}
+	manifest.Arch = map[string]map[string]string{}
+	archiveExt := map[string]string{}
+	nameTemplate := ""
+	for _, p := range platforms {
+		if archives && p.ext == "" {
+			return false
+		}
+
+		name := p.stem
+		start, end := findToken(name, osAliases(p.goos))
+		if start < 0 {
+			return false
+		}
+		osValue := name[start:end]
+		name = name[:start] + "\x00" + name[end:]
+
+		start, end = findToken(name, archAliases(p.artifact))
+		if start < 0 {
+			return false
+		}
+		archValue := name[start:end]
+		name = name[:start] + "\x01" + name[end:]
+
+		name = strings.ReplaceAll(name, manifest.Version, "\x02")
+		name = strings.NewReplacer("\x00", "{{.Os}}", "\x01", "{{.Arch}}", "\x02", "{{.Version}}").Replace(name)
+		if nameTemplate != "" && name != nameTemplate {
+			return false
+		}
+		nameTemplate = name
+
+		if existing, ok := manifest.Os[p.goos]; ok && existing != osValue {
+			return false
+		}
+		manifest.Os[p.goos] = osValue
+		if manifest.Arch[osValue] == nil {
+			manifest.Arch[osValue] = map[string]string{}
+		}
+		manifest.Arch[osValue][p.key] = archValue
+
+		if existing, ok := archiveExt[p.goos]; ok && existing != p.ext {
+			return false
+		}
+		archiveExt[p.goos] = p.ext
+	}
+
+	if archives {
+		manifest.Archive = nameTemplate + "{{.ArchiveExt}}"
+		for goos, ext := range archiveExt {
+			if ext != defaultArchiveExt(goos) {
+				if manifest.ArchiveExt == nil {
+					manifest.ArchiveExt = map[string]string{}
+				}
+				manifest.ArchiveExt[goos] = ext
+			}
+		}
+	} else {
+		manifest.Binary = nameTemplate + "{{.Ext}}"
+	}
+
+	return rendersNames(manifest, platforms)
+}
+
+// literalNames lists the name of each platform in the arch map, for names
+// that cannot be templated.
+func literalNames(manifest *updater.UpdaterManifest, platforms []*platform, archives bool) {
+	manifest.Os = map[string]string{}
+	manifest.Arch = map[string]map[string]string{}
+	manifest.ArchiveExt = nil
+	for _, p := range platforms {
+		manifest.Os[p.goos] = p.goos
+		if manifest.Arch[p.goos] == nil {
+			manifest.Arch[p.goos] = map[string]string{}
+		}
+		manifest.Arch[p.goos][p.key] = p.artifact.Name
+	}
+
+	if archives {
+		manifest.Archive = "{{.Arch}}"
+	} else {
+		manifest.Binary = "{{.Arch}}"
+	}
+}
+
+// rendersNames reports whether the manifest renders the name of the artifact
+// of every platform.
+func rendersNames(manifest *updater.UpdaterManifest, platforms []*platform) bool {
+	urls, err := manifest.AllDownloadURLs("https://manifestgen.invalid/")
+	if err != nil || len(urls) != len(platforms) {
+		return false
+	}
+
+	for _, p := range platforms {
+		parsed, err := url.Parse(urls[p.goos+"/"+p.key])
+		if err != nil || path.Base(parsed.Path) != p.artifact.Name {
+			return false
+		}
+	}
+
+	return true
+}
+
+func defaultArchiveExt(goos string) string {
+	if goos == "windows" {
+		return ".zip"
+	}
+
+	return ".tar.gz"
+}
+
+func osAliases(goos string) []string {
+	switch goos {
+	case "darwin":
+		return []string{"darwin", "macos", "mac", "osx", "apple"}
+	case "windows":
+		return []string{"windows", "win"}
+	}
+
+	return []string{goos}
+}
+
+// archAliases returns the names GoReleaser archive templates commonly use
+// for the arch of the artifact, most specific first.
+func archAliases(artifact *Artifact) []string {
+	var aliases []string
+	switch artifact.Goarch {
+	case "amd64":
+		aliases = []string{"amd64", "x86_64", "x86-64", "x64", "64bit", "64-bit"}
+	case "386":
+		aliases = []string{"386", "i386", "i686", "x86", "32bit", "32-bit"}
+	case "arm64":
+		aliases = []string{"arm64", "aarch64"}
+	case "arm":
+		if artifact.Goarm != "" {
+			aliases = append(aliases, "armv"+artifact.Goarm, "arm"+artifact.Goarm)
+		}
+		aliases = append(aliases, "armhf", "arm")
+	case "all":
+		aliases = []string{"all", "universal"}
+	default:
+		aliases = []string{artifact.Goarch}
+	}
+
+	level := artifact.Goamd64
+	if artifact.Goarch != "amd64" || level == "" || level == "v1" {
+		return aliases
+	}
+
+	var leveled []string
+	for _, alias := range aliases {
+		leveled = append(leveled, alias+"_"+level, alias+"-"+level, alias+level)
+	}
+	return append(leveled, aliases...)
+}
+
+// findToken finds the last occurrence of the first of tokens found in name,
+// ignoring case, that is delimited by separators or the ends of the name.
+func findToken(name string, tokens []string) (int, int) {
+	lower := strings.ToLower(name)
+	for _, token := range tokens {
+		token = strings.ToLower(token)
+		for end := len(lower); end > 0; {
+			start := strings.LastIndex(lower[:end], token)
+			if start < 0 {
+				break
+			}
+			if isSeparator(lower, start-1) && isSeparator(lower, start+len(token)) {
+				return start, start + len(token)
+			}
+			end = start + len(token) - 1
+		}
+	}
+
+	return -1, -1
+}
+
+func isSeparator(name string, i int) bool {
+	return i < 0 || i >= len(name) || strings.IndexByte("_-. \x00\x01", name[i]) >= 0
+}
+
+// addChecksums adds the SHA-256 checksums and sizes of the artifacts, hashing
+// the files, or else using the checksums GoReleaser recorded.
+func addChecksums(manifest *updater.UpdaterManifest, platforms []*platform, dist string) error {
+	for _, p := range platforms {
+		name := p.artifact.Name
+		if _, ok := manifest.Checksums[name]; ok {
+			continue
+		}
+
+		if path := p.artifact.resolve(dist); path != "" {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			err = manifest.AddAsset(name, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		checksum, ok := strings.CutPrefix(p.artifact.Extra.Checksum, "sha256:")
+		if !ok {
+			return fmt.Errorf("Cannot checksum %s. The file was not found and GoReleaser did not record its sha256 checksum", name)
+		}
+		if manifest.Checksums == nil {
+			manifest.Checksums = map[string]string{}
+		}
+		manifest.Checksums[name] = checksum
+	}
+
+	return nil
+}
@@ -0,0 +1,357 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestSchemaVersion is the newest manifest schemaVersion this updater
+// reads.
+//
+//   - 1: Manifests without a schemaVersion. Keys match fields regardless of
+//     case and unknown keys are ignored.
+//   - 2: Keys must match exactly and unknown keys are rejected, catching typos
+//     before clients see the manifest.
+//
+// Manifests of an older schema are migrated to the current one when read.
+// Manifests of a newer schema are read as far as this updater understands
+// them, ignoring unknown keys, so that publishers can adopt a new schema
+// without breaking deployed clients.
+const ManifestSchemaVersion = 2
+
+// manifestMigrations migrate a manifest decoded from JSON from the schema
+// version of the index to the next.
+var manifestMigrations = map[int]func(tree map[string]any){
+	1: canonicalizeKeys,
+}
+
+// decodeManifest decodes a JSON manifest of any schema version, migrating and
+// validating it.
+func decodeManifest(data []byte) (*UpdaterManifest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree map[string]any
+	err := decoder.Decode(&tree)
+	if err != nil {
+		return nil, fmt.Errorf("%w. %w", ErrManifestInvalid, err)
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("%w. Expected an object", ErrManifestInvalid)
+	}
+
+	schemaVersion := 1
+	if value, ok := tree["schemaVersion"]; ok {
+		number, isNumber := value.(json.Number)
+		parsed, err := number.Int64()
+		if !isNumber || err != nil || parsed < 1 {
+			return nil, fmt.Errorf("%w. schemaVersion must be a positive integer but got %v", ErrManifestInvalid, value)
+		}
+		schemaVersion = int(parsed)
+	}
+
+	for version := schemaVersion; version < ManifestSchemaVersion; version++ {
+		if migrate := manifestMigrations[version]; migrate != nil {
+			migrate(tree)
+		}
+	}
+	if schemaVersion >= 2 && schemaVersion <= ManifestSchemaVersion {
+		problems := unknownKeys(tree)
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("%w. %s", ErrManifestInvalid, strings.Join(problems, "; "))
+		}
+	}
+
+	data, err = json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("%w. %w", ErrManifestInvalid, err)
+	}
+	var manifest UpdaterManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w. %w", ErrManifestInvalid, err)
+	}
+	manifest.SchemaVersion = schemaVersion
+
+	err = manifest.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+var (
+	manifestType = reflect.TypeOf(UpdaterManifest{})
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// jsonFields returns the types of the fields of struct type t by JSON key.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+// walkKeys walks value, decoded from JSON, along type t. resolve is called
+// for every key of an object decoded into a struct and returns the key to
+// walk into, or false to skip it.
+func walkKeys(path string, value any, t reflect.Type, resolve func(path string, object map[string]any, key string, fields map[string]reflect.Type) (string, bool)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok || t == timeType {
+			return
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			resolved, ok := resolve(path, object, key, fields)
+			if ok {
+				walkKeys(joinKeyPath(path, resolved), object[resolved], fields[resolved], resolve)
+			}
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		for key, item := range object {
+			walkKeys(joinKeyPath(path, key), item, t.Elem(), resolve)
+		}
+	case reflect.Slice:
+		items, ok := value.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			walkKeys(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), resolve)
+		}
+	}
+}
+
+func joinKeyPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// canonicalizeKeys migrates schema 1 manifests, renaming keys that match a
+// field regardless of case, e.g., Version, to the key of the field, as
+// encoding/json matched them.
+func canonicalizeKeys(tree map[string]any) {
+	walkKeys("", tree, manifestType, func(path string, object map[string]any, key string, fields map[string]reflect.Type) (string, bool) {
+		if _, ok := fields[key]; ok {
+			return key, true
+		}
+
+		for field := range fields {
+			if strings.EqualFold(field, key) {
+				if _, exists := object[field]; !exists {
+					object[field] = object[key]
+					delete(object, key)
+				}
+				return field, true
+			}
+		}
+
+		return "", false
+	})
+}
+
+// unknownKeys describes the keys of the manifest that match no field.
+func unknownKeys(tree map[string]any) []string {
+	var problems []string
+	walkKeys("", tree, manifestType, func(path string, object map[string]any, key string, fields map[string]reflect.Type) (string, bool) {
+		if _, ok := fields[key]; ok {
+			return key, true
+		}
+
+		problem := fmt.Sprintf("Unknown key %q", joinKeyPath(path, key))
+		if suggestion := closestKey(key, fields); suggestion != "" {
+			problem += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		problems = append(problems, problem)
+		return "", false
+	})
+
+	return problems
+}
+
+// closestKey suggests the field key closest to key, when close enough to be a
+// typo.
+func closestKey(key string, fields map[string]reflect.Type) string {
+	closest := ""
+	best := 3
+	for field := range fields {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(field))
+		if distance < best || (distance == best && field < closest) {
+			closest = field
+			best = distance
+		}
+	}
+
+	return closest
+}
+
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// Validate checks that the manifest has the required fields and that its
+// templates, checksums and rollout are valid, describing every problem found.
+// Channels and versions only override the top level and are checked for
+// invalid values alone.
+func (manifest *UpdaterManifest) Validate() error {
+	var problems []string
+	if strings.TrimSpace(manifest.Version) == "" {
+		problems = append(problems, `Missing required key "version"`)
+	}
+	if strings.TrimSpace(manifest.Binary) == "" {
+		problems = append(problems, `Missing required key "binary"`)
+	}
+	if len(manifest.Os) == 0 {
+		problems = append(problems, `Missing required key "os", map every supported runtime.GOOS, e.g., "linux": "linux"`)
+	}
+	if len(manifest.Arch) == 0 {
+		problems = append(problems, `Missing required key "arch", map every supported runtime.GOARCH by os, e.g., "linux": {"amd64": "x86_64"}`)
+	}
+	goosList := make([]string, 0, len(manifest.Os))
+	for goos := range manifest.Os {
+		goosList = append(goosList, goos)
+	}
+	sort.Strings(goosList)
+	for _, goos := range goosList {
+		os := manifest.Os[goos]
+		if len(manifest.Arch) > 0 && len(manifest.Arch[os]) == 0 {
+			problems = append(problems, fmt.Sprintf("Missing key %q, map the architectures of os %s", "arch."+os, goos))
+		}
+	}
+
+	problems = append(problems, manifest.invalidValues("")...)
+
+	channels := make([]string, 0, len(manifest.Channels))
+	for channel := range manifest.Channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		if manifest.Channels[channel] != nil {
+			problems = append(problems, manifest.Channels[channel].invalidValues("channels."+channel+".")...)
+		}
+	}
+
+	versions := make([]string, 0, len(manifest.Versions))
+	for version := range manifest.Versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	for _, version := range versions {
+		if manifest.Versions[version] != nil {
+			problems = append(problems, manifest.Versions[version].invalidValues("versions."+version+".")...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w. %s", ErrManifestInvalid, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// invalidValues describes the invalid templates, checksums and rollout of the
+// manifest, their keys prefixed with prefix.
+func (manifest *UpdaterManifest) invalidValues(prefix string) []string {
+	var problems []string
+	templates := []struct {
+		key  string
+		text string
+	}{
+		{"archive", manifest.Archive},
+		{"binary", manifest.Binary},
+		{"migration", manifest.Migration},
+	}
+	for version, patch := range manifest.Patches {
+		templates = append(templates, struct {
+			key  string
+			text string
+		}{"patches." + version, patch})
+	}
+	sort.SliceStable(templates[3:], func(i, j int) bool {
+		return templates[3+i].key < templates[3+j].key
+	})
+	for _, tmpl := range templates {
+		if strings.TrimSpace(tmpl.text) == "" {
+			continue
+		}
+		_, err := renderTemplate(tmpl.key, tmpl.text, &variables{})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Invalid template %q. %s", prefix+tmpl.key, err))
+		}
+	}
+
+	names := make([]string, 0, len(manifest.Checksums))
+	for name := range manifest.Checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checksum, err := hex.DecodeString(strings.TrimSpace(manifest.Checksums[name]))
+		if err != nil || len(checksum) != 32 {
+			problems = append(problems, fmt.Sprintf("Invalid checksum %q. Expected a hex encoded SHA-256 checksum", prefix+"checksums."+name))
+		}
+	}
+
+	if manifest.Rollout != nil && (manifest.Rollout.Percent < 0 || manifest.Rollout.Percent > 100) {
+		problems = append(problems, fmt.Sprintf("Invalid %q %v. Expected a percentage between 0 and 100", prefix+"rollout.percent", manifest.Rollout.Percent))
+	}
+
+	return problems
+}
@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrMetadataMismatch = errors.New("Manifest does not match the signed metadata")
+
+// signedMetadata pins the manifest by checksum so the manifest itself does
+// not need to be signed.
+type signedMetadata struct {
+	Version string `json:"version"`
+	Sha256  string `json:"sha256"`
+}
+
+func (updater *Updater) metadataName() string {
+	if updater.config.MetadataFile != "" {
+		return updater.config.MetadataFile
+	}
+
+	return "metadata.json"
+}
+
+func (updater *Updater) fetchSignedMetadata(ctx context.Context) (*signedMetadata, error) {
+	key, err := parsePublicKey(updater.config.MetadataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	name := updater.metadataName()
+	data, err := updater.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sigName, err := signatureName(name)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := updater.fetch(ctx, sigName)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid signature for %s. %w", name, err)
+	}
+	if !ed25519.Verify(key, data, signature) {
+		return nil, fmt.Errorf("Signature verification failed for %s", name)
+	}
+
+	var metadata signedMetadata
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid metadata %s. %w", name, err)
+	}
+
+	return &metadata, nil
+}
+
+func (updater *Updater) verifyManifestMetadata(ctx context.Context, data []byte, manifest *UpdaterManifest) error {
+	metadata, err := updater.fetchSignedMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if strings.ToLower(metadata.Sha256) != actual {
+		return fmt.Errorf("%w. Expected checksum %s but got %s", ErrMetadataMismatch, metadata.Sha256, actual)
+	}
+
+	if metadata.Version != "" && strings.TrimSpace(metadata.Version) != strings.TrimSpace(manifest.Version) {
+		return fmt.Errorf("%w. Expected version %s but got %s", ErrMetadataMismatch, metadata.Version, manifest.Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of checks and updates, e.g., to monitor
+// self-updates across a fleet. PrometheusMetrics exports them in the
+// Prometheus text format. Methods are called on the goroutine running the
+// check or update and must not block.
+type Metrics interface {
+	// CheckCompleted is called after each check for updates with the outcome
+	// available, none or error.
+	CheckCompleted(outcome string)
+	// UpdateCompleted is called after each update with the outcome success,
+	// failure or deferred, and the error class of failed updates as sent to
+	// the ReportEndpoint.
+	UpdateCompleted(outcome string, errorClass string)
+	// DownloadedBytes is called as archives, binaries and patches are
+	// downloaded.
+	DownloadedBytes(n int64)
+	// AppliedIn is called with the time it took to install an update, from
+	// replacing the binary through the SmokeTest, HealthCheck, migration and
+	// AfterApply, whether the update succeeded or not.
+	AppliedIn(duration time.Duration)
+}
+
+func (updater *Updater) recordCheck(info *UpdateInfo, err error) {
+	if updater.config.Metrics == nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		updater.config.Metrics.CheckCompleted("error")
+	case info != nil:
+		updater.config.Metrics.CheckCompleted("available")
+	default:
+		updater.config.Metrics.CheckCompleted("none")
+	}
+}
+
+func (updater *Updater) recordUpdate(start time.Time, info *downloadInfo, err error) {
+	if updater.config.Metrics == nil {
+		return
+	}
+
+	report := updater.newReport(start, info, err)
+	updater.config.Metrics.UpdateCompleted(report.Outcome, report.ErrorClass)
+}
+
+func (updater *Updater) recordApply(start time.Time) {
+	if updater.config.Metrics != nil {
+		updater.config.Metrics.AppliedIn(time.Since(start))
+	}
+}
+
+// applyDurationBuckets are the upper bounds in seconds of the
+// updater_apply_duration_seconds histogram.
+var applyDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// PrometheusMetrics collects Metrics and exports them in the Prometheus text
+// exposition format, with no dependency on the Prometheus client. Serve it as
+// a scrape endpoint, e.g., http.Handle("/metrics", metrics), or write it to a
+// file for the node_exporter textfile collector with WriteTo.
+//
+//	updater_checks_total{outcome="available|none|error"}
+//	updater_updates_total{outcome="success|failure|deferred",error_class="..."}
+//	updater_download_bytes_total
+//	updater_apply_duration_seconds (histogram)
+type PrometheusMetrics struct {
+	mu            sync.Mutex
+	checks        map[string]uint64
+	updates       map[[2]string]uint64
+	downloadBytes int64
+	applyBuckets  []uint64
+	applyCount    uint64
+	applySum      float64
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		checks:       map[string]uint64{},
+		updates:      map[[2]string]uint64{},
+		applyBuckets: make([]uint64, len(applyDurationBuckets)),
+	}
+}
+
+func (metrics *PrometheusMetrics) CheckCompleted(outcome string) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	metrics.checks[outcome]++
+}
+
+func (metrics *PrometheusMetrics) UpdateCompleted(outcome string, errorClass string) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	metrics.updates[[2]string{outcome, errorClass}]++
+}
+
+func (metrics *PrometheusMetrics) DownloadedBytes(n int64) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	metrics.downloadBytes += n
+}
+
+func (metrics *PrometheusMetrics) AppliedIn(duration time.Duration) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	seconds := duration.Seconds()
+	for i, bound := range applyDurationBuckets {
+		if seconds <= bound {
+			metrics.applyBuckets[i]++
+		}
+	}
+	metrics.applyCount++
+	metrics.applySum += seconds
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (metrics *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	metrics.mu.Lock()
+	b.WriteString("# HELP updater_checks_total Checks for updates by outcome.\n")
+	b.WriteString("# TYPE updater_checks_total counter\n")
+	outcomes := make([]string, 0, len(metrics.checks))
+	for outcome := range metrics.checks {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+	for _, outcome := range outcomes {
+		fmt.Fprintf(&b, "updater_checks_total{outcome=\"%s\"} %d\n", labelEscaper.Replace(outcome), metrics.checks[outcome])
+	}
+
+	b.WriteString("# HELP updater_updates_total Updates by outcome and error class.\n")
+	b.WriteString("# TYPE updater_updates_total counter\n")
+	updates := make([][2]string, 0, len(metrics.updates))
+	for labels := range metrics.updates {
+		updates = append(updates, labels)
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i][0] != updates[j][0] {
+			return updates[i][0] < updates[j][0]
+		}
+		return updates[i][1] < updates[j][1]
+	})
+	for _, labels := range updates {
+		fmt.Fprintf(&b, "updater_updates_total{outcome=\"%s\",error_class=\"%s\"} %d\n", labelEscaper.Replace(labels[0]), labelEscaper.Replace(labels[1]), metrics.updates[labels])
+	}
+
+	b.WriteString("# HELP updater_download_bytes_total Bytes of archives, binaries and patches downloaded.\n")
+	b.WriteString("# TYPE updater_download_bytes_total counter\n")
+	fmt.Fprintf(&b, "updater_download_bytes_total %d\n", metrics.downloadBytes)
+
+	b.WriteString("# HELP updater_apply_duration_seconds Time taken to install updates.\n")
+	b.WriteString("# TYPE updater_apply_duration_seconds histogram\n")
+	for i, bound := range applyDurationBuckets {
+		fmt.Fprintf(&b, "updater_apply_duration_seconds_bucket{le=\"%g\"} %d\n", bound, metrics.applyBuckets[i])
+	}
+	fmt.Fprintf(&b, "updater_apply_duration_seconds_bucket{le=\"+Inf\"} %d\n", metrics.applyCount)
+	fmt.Fprintf(&b, "updater_apply_duration_seconds_sum %g\n", metrics.applySum)
+	fmt.Fprintf(&b, "updater_apply_duration_seconds_count %d\n", metrics.applyCount)
+	metrics.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (metrics *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = metrics.WriteTo(w)
+}
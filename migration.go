@@ -0,0 +1,42 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var ErrMigrationFailed = errors.New("Migration failed")
+
+// runMigration runs the migration shipped in the archive once the new binary
+// is in place. The migration finds the new binary and the versions involved
+// in the UPDATER_BINARY, UPDATER_VERSION and UPDATER_PREVIOUS_VERSION
+// environment variables.
+func (updater *Updater) runMigration(info *downloadInfo, installed *installation) error {
+	if installed.migrationPath == "" {
+		return nil
+	}
+	if !installed.dir {
+		defer os.Remove(installed.migrationPath)
+	}
+
+	err := os.Chmod(installed.migrationPath, 0744)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(installed.migrationPath)
+	cmd.Env = append(os.Environ(),
+		"UPDATER_BINARY="+installed.binaryPath,
+		"UPDATER_VERSION="+strings.TrimSpace(info.manifest.Version),
+		"UPDATER_PREVIOUS_VERSION="+strings.TrimSpace(updater.config.CurrentVersion),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w. %w. %s", ErrMigrationFailed, err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
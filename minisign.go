@@ -0,0 +1,181 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+type minisignPublicKey struct {
+	keyId [8]byte
+	key   ed25519.PublicKey
+}
+
+type minisignSignature struct {
+	algorithm       string
+	keyId           [8]byte
+	signature       []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+// lastBase64Line returns the last line that is not a comment, so that both
+// the bare base64 key and the contents of a minisign .pub file are accepted.
+func lastBase64Line(data string) string {
+	last := ""
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			last = line
+		}
+	}
+
+	return last
+}
+
+func parseMinisignPublicKey(encoded string) (*minisignPublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(lastBase64Line(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid minisign public key. %w", err)
+	}
+	if len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != "Ed" {
+		return nil, fmt.Errorf("Invalid minisign public key. Expected an Ed25519 key")
+	}
+
+	key := &minisignPublicKey{key: ed25519.PublicKey(data[10:])}
+	copy(key.keyId[:], data[2:10])
+	return key, nil
+}
+
+func parseMinisignSignature(data []byte) (*minisignSignature, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("Invalid minisign signature. Expected 4 lines")
+	}
+
+	encoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid minisign signature. %w", err)
+	}
+	if len(encoded) != 2+8+ed25519.SignatureSize {
+		return nil, fmt.Errorf("Invalid minisign signature. Unexpected length %d", len(encoded))
+	}
+
+	trustedComment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return nil, fmt.Errorf("Invalid minisign signature. Missing trusted comment")
+	}
+
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid minisign global signature. %w", err)
+	}
+
+	signature := &minisignSignature{
+		algorithm:       string(encoded[:2]),
+		signature:       encoded[10:],
+		trustedComment:  trustedComment,
+		globalSignature: globalSignature,
+	}
+	copy(signature.keyId[:], encoded[2:10])
+	return signature, nil
+}
+
+// verify checks a minisign signature of data, either the legacy Ed signature
+// of the data itself or the ED signature of its BLAKE2b-512 hash. Prehashed
+// data is streamed, only legacy signatures require the data in memory.
+func (key *minisignPublicKey) verify(data io.Reader, signature *minisignSignature) error {
+	if !bytes.Equal(key.keyId[:], signature.keyId[:]) {
+		return fmt.Errorf("Signed with key %X but expected key %X", signature.keyId, key.keyId)
+	}
+
+	var message []byte
+	switch signature.algorithm {
+	case "Ed":
+		var err error
+		message, err = io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+	case "ED":
+		hash, err := blake2b.New512(nil)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(hash, data)
+		if err != nil {
+			return err
+		}
+		message = hash.Sum(nil)
+	default:
+		return fmt.Errorf("Unsupported minisign signature algorithm %q", signature.algorithm)
+	}
+
+	if !ed25519.Verify(key.key, message, signature.signature) {
+		return fmt.Errorf("Invalid signature")
+	}
+
+	global := append(append([]byte{}, signature.signature...), signature.trustedComment...)
+	if !ed25519.Verify(key.key, global, signature.globalSignature) {
+		return fmt.Errorf("Invalid trusted comment signature")
+	}
+
+	return nil
+}
+
+func (updater *Updater) verifyMinisign(name string, data io.Reader, encoded []byte) error {
+	key, err := parseMinisignPublicKey(updater.config.MinisignPublicKey)
+	if err != nil {
+		return err
+	}
+
+	signature, err := parseMinisignSignature(encoded)
+	if err != nil {
+		return fmt.Errorf("Error verifying %s. %w", name, err)
+	}
+
+	err = key.verify(data, signature)
+	if err != nil {
+		return fmt.Errorf("Signature verification failed for %s. %w", name, err)
+	}
+
+	return nil
+}
+
+func (updater *Updater) verifyManifestMinisign(ctx context.Context, data []byte) error {
+	nameUrl, err := url.Parse(updater.config.UpdaterConfig)
+	if err != nil {
+		return err
+	}
+	nameUrl.Path += ".minisig"
+
+	encoded, err := updater.fetch(ctx, nameUrl.String())
+	if err != nil {
+		return err
+	}
+
+	return updater.verifyMinisign(updater.config.UpdaterConfig, bytes.NewReader(data), encoded)
+}
+
+func (updater *Updater) verifyArtifactMinisign(ctx context.Context, info *downloadInfo, name string, path string) error {
+	encoded, err := updater.fetchAsset(ctx, info, name+".minisig")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return updater.verifyMinisign(name, file, encoded)
+}
@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	minisignKeyId   = [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	minisignOtherId = [8]byte{8, 7, 6, 5, 4, 3, 2, 1}
+	minisignData    = []byte("binary")
+	minisignComment = "timestamp:1700000000\tfile:app.tar.gz"
+)
+
+// minisignPublicKeyFile encodes key like the .pub file written by minisign -G.
+func minisignPublicKeyFile(keyId [8]byte, key ed25519.PrivateKey) string {
+	data := append(append([]byte("Ed"), keyId[:]...), key.Public().(ed25519.PublicKey)...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(data) + "\n"
+}
+
+// minisignSign returns the .minisig file minisign -S writes for data, with
+// the legacy Ed or the prehashed ED algorithm.
+func minisignSign(algorithm string, keyId [8]byte, key ed25519.PrivateKey, data []byte, comment string) []byte {
+	message := data
+	if algorithm == "ED" {
+		hash := blake2b.Sum512(data)
+		message = hash[:]
+	}
+	signature := ed25519.Sign(key, message)
+	global := ed25519.Sign(key, append(append([]byte{}, signature...), comment...))
+
+	encoded := append(append([]byte(algorithm), keyId[:]...), signature...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(encoded) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	key, otherKey := testKey(1), testKey(2)
+	publicKey := minisignPublicKeyFile(minisignKeyId, key)
+
+	prehashed := minisignSign("ED", minisignKeyId, key, minisignData, minisignComment)
+	lines := strings.Split(string(prehashed), "\n")
+	encoded, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := strings.Join([]string{lines[0], base64.StdEncoding.EncodeToString(encoded[:len(encoded)-1]), lines[2], lines[3]}, "\n")
+	unknownAlgorithm := strings.Join([]string{lines[0], base64.StdEncoding.EncodeToString(append([]byte("Ex"), encoded[2:]...)), lines[2], lines[3]}, "\n")
+	tamperedComment := strings.Replace(string(prehashed), "file:app.tar.gz", "file:other.tar.gz", 1)
+
+	tests := []struct {
+		name      string
+		publicKey string
+		data      []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{name: "prehashed", publicKey: publicKey, data: minisignData, signature: prehashed},
+		{name: "legacy", publicKey: publicKey, data: minisignData, signature: minisignSign("Ed", minisignKeyId, key, minisignData, minisignComment)},
+		{name: "bare public key", publicKey: strings.Split(publicKey, "\n")[1], data: minisignData, signature: prehashed},
+		{name: "crlf line endings", publicKey: publicKey, data: minisignData, signature: bytes.ReplaceAll(prehashed, []byte("\n"), []byte("\r\n"))},
+		{name: "tampered data", publicKey: publicKey, data: []byte("binary2"), signature: prehashed, wantErr: true},
+		{name: "legacy tampered data", publicKey: publicKey, data: []byte("binary2"), signature: minisignSign("Ed", minisignKeyId, key, minisignData, minisignComment), wantErr: true},
+		{name: "wrong key id", publicKey: publicKey, data: minisignData, signature: minisignSign("ED", minisignOtherId, key, minisignData, minisignComment), wantErr: true},
+		{name: "wrong key", publicKey: publicKey, data: minisignData, signature: minisignSign("ED", minisignKeyId, otherKey, minisignData, minisignComment), wantErr: true},
+		{name: "prehash mismatch", publicKey: publicKey, data: minisignData, signature: []byte(strings.Replace(string(prehashed), lines[1], base64.StdEncoding.EncodeToString(append([]byte("Ed"), encoded[2:]...)), 1)), wantErr: true},
+		{name: "truncated signature", publicKey: publicKey, data: minisignData, signature: []byte(truncated), wantErr: true},
+		{name: "unknown algorithm", publicKey: publicKey, data: minisignData, signature: []byte(unknownAlgorithm), wantErr: true},
+		{name: "tampered trusted comment", publicKey: publicKey, data: minisignData, signature: []byte(tamperedComment), wantErr: true},
+		{name: "missing trusted comment", publicKey: publicKey, data: minisignData, signature: []byte(strings.Join([]string{lines[0], lines[1], minisignComment, lines[3]}, "\n")), wantErr: true},
+		{name: "missing global signature", publicKey: publicKey, data: minisignData, signature: []byte(strings.Join(lines[:3], "\n")), wantErr: true},
+		{name: "invalid public key", publicKey: "untrusted comment: key\n!", data: minisignData, signature: prehashed, wantErr: true},
+		{name: "truncated public key", publicKey: base64.StdEncoding.EncodeToString([]byte("Ed12345678")), data: minisignData, signature: prehashed, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updater := New(&UpdaterConfig{MinisignPublicKey: test.publicKey})
+			err := updater.verifyMinisign("app.tar.gz", bytes.NewReader(test.data), test.signature)
+			if test.wantErr && err == nil {
+				t.Fatal("verifyMinisign() succeeded, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("verifyMinisign() error = %v", err)
+			}
+		})
+	}
+}
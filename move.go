@@ -0,0 +1,64 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// moveFile renames src to destination. When they are on different volumes,
+// e.g., a download staged in the temp dir, src is first copied next to
+// destination so that destination is still replaced by a rename. src is then
+// removed best effort.
+func moveFile(src string, destination string) error {
+	err := renameFile(src, destination)
+	if err == nil || !crossDevice(err) {
+		return err
+	}
+
+	tempPath := filepath.Join(filepath.Dir(destination), "."+filepath.Base(destination)+"."+uuid.NewString())
+	err = copyFileMode(src, tempPath)
+	if err == nil {
+		err = renameFile(tempPath, destination)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	os.Remove(src)
+	return nil
+}
+
+// copyFileMode copies src to destination with the permissions of src.
+func copyFileMode(src string, destination string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	err = copyFile(src, destination)
+	if err == nil {
+		err = os.Chmod(destination, info.Mode().Perm())
+	}
+	if err != nil {
+		os.Remove(destination)
+	}
+	return err
+}
+
+// syncFile flushes the file at path to disk.
+func syncFile(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	err = file.Sync()
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
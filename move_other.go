@@ -0,0 +1,28 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package updater
+
+import (
+	"errors"
+	"os"
+)
+
+func renameFile(src string, destination string) error {
+	return os.Rename(src, destination)
+}
+
+func crossDevice(err error) bool {
+	return false
+}
+
+func removeOnReboot(path string) error {
+	return errors.ErrUnsupported
+}
+
+func backupBinary(binaryPath string, backupPath string) error {
+	return copyFileMode(binaryPath, backupPath)
+}
+
+func syncDir(dir string) error {
+	return nil
+}
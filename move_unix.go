@@ -0,0 +1,43 @@
+//go:build linux || darwin || freebsd
+
+package updater
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func renameFile(src string, destination string) error {
+	return os.Rename(src, destination)
+}
+
+func crossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+func removeOnReboot(path string) error {
+	return errors.ErrUnsupported
+}
+
+// backupBinary links, or copies, the binary to backupPath, leaving the binary
+// in place to be replaced by a single rename.
+func backupBinary(binaryPath string, backupPath string) error {
+	err := os.Link(binaryPath, backupPath)
+	if err == nil {
+		return nil
+	}
+
+	return copyFileMode(binaryPath, backupPath)
+}
+
+// syncDir flushes the entries of dir, e.g., a rename, to disk.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
@@ -0,0 +1,52 @@
+package updater
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const renameAttempts = 5
+
+// renameFile retries renames failing because another process, e.g., an
+// antivirus scanner, briefly holds the file open.
+func renameFile(src string, destination string) error {
+	var err error
+	for attempt := 1; attempt <= renameAttempts; attempt++ {
+		err = os.Rename(src, destination)
+		if err == nil || !(errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_ACCESS_DENIED)) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+	}
+
+	return err
+}
+
+func crossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
+
+// removeOnReboot schedules path for deletion on the next reboot, e.g., a
+// previous binary still running. It requires administrator privileges.
+func removeOnReboot(path string) error {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	return windows.MoveFileEx(pathPtr, nil, windows.MOVEFILE_DELAY_UNTIL_REBOOT)
+}
+
+// backupBinary moves the binary to backupPath. The running executable can be
+// renamed but not replaced on Windows.
+func backupBinary(binaryPath string, backupPath string) error {
+	return renameFile(binaryPath, backupPath)
+}
+
+// syncDir is a no-op, directories cannot be flushed on Windows.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,310 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+var ociManifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+type ociReference struct {
+	registry   string
+	repository string
+	reference  string
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// parseOciReference parses oci://registry/repository[:tag|@digest] urls.
+func parseOciReference(alternate *url.URL) (*ociReference, error) {
+	repository := strings.Trim(alternate.Path, "/")
+	if alternate.Host == "" || repository == "" {
+		return nil, fmt.Errorf("Invalid OCI reference %s. Expected oci://registry/repository:tag", alternate)
+	}
+
+	reference := "latest"
+	if i := strings.LastIndex(repository, "@"); i >= 0 {
+		reference = repository[i+1:]
+		repository = repository[:i]
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		reference = repository[i+1:]
+		repository = repository[:i]
+	}
+
+	return &ociReference{
+		registry:   alternate.Host,
+		repository: repository,
+		reference:  reference,
+	}, nil
+}
+
+func parseDigest(digest string) (string, error) {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(hexDigest) != sha256.Size*2 {
+		return "", fmt.Errorf("Unsupported digest %s. Only sha256 digests are supported", digest)
+	}
+
+	return strings.ToLower(hexDigest), nil
+}
+
+type ociClient struct {
+	reference      *ociReference
+	httpClient     *http.Client
+	prepareRequest func(request *http.Request)
+	// credentials are the RegistryAuth of the registry, sent to the
+	// registry and to its token realm.
+	credentials *BasicAuth
+	token       string
+}
+
+func parseAuthenticateHeader(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(header, " ")
+	params := make(map[string]string)
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return scheme, params
+}
+
+func (client *ociClient) authenticate(ctx context.Context, challenge string) error {
+	scheme, params := parseAuthenticateHeader(challenge)
+	if !strings.EqualFold(scheme, "Bearer") || params["realm"] == "" {
+		return fmt.Errorf("Unsupported registry authentication %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return err
+	}
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + client.reference.repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	err = validateUrl(realm.String())
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if client.credentials != nil {
+		request.SetBasicAuth(client.credentials.Username, client.credentials.Password)
+	}
+	client.prepareRequest(request)
+	resp, err := client.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error getting registry token. %w", httpStatusError(resp))
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return fmt.Errorf("Invalid registry token response. %w", err)
+	}
+
+	client.token = token.Token
+	if client.token == "" {
+		client.token = token.AccessToken
+	}
+
+	return nil
+}
+
+func (client *ociClient) get(ctx context.Context, endpoint string, accept []string) (*http.Response, error) {
+	requestUrl := "https://" + client.reference.registry + path.Join("/v2", client.reference.repository, endpoint)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, mediaType := range accept {
+			request.Header.Add("Accept", mediaType)
+		}
+		client.prepareRequest(request)
+		if client.token != "" {
+			request.Header.Set("Authorization", "Bearer "+client.token)
+		} else if client.credentials != nil {
+			request.SetBasicAuth(client.credentials.Username, client.credentials.Password)
+		}
+
+		resp, err := client.httpClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		if resp.StatusCode != http.StatusUnauthorized || challenge == "" || client.token != "" {
+			return nil, fmt.Errorf("Error downloading %s. %w", redactUrlString(requestUrl), httpStatusError(resp))
+		}
+
+		err = client.authenticate(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("Error downloading %s. Registry rejected the token", redactUrlString(requestUrl))
+}
+
+func (client *ociClient) manifest(ctx context.Context) (*ociManifest, error) {
+	resp, err := client.get(ctx, "manifests/"+client.reference.reference, ociManifestMediaTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := readLimited("the OCI manifest", resp.ContentLength, resp.Body, maxSidecarBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(client.reference.reference, "sha256:") {
+		expected, err := parseDigest(client.reference.reference)
+		if err != nil {
+			return nil, err
+		}
+		actual := sha256.Sum256(data)
+		if hex.EncodeToString(actual[:]) != expected {
+			return nil, fmt.Errorf("OCI manifest does not match digest %s", client.reference.reference)
+		}
+	}
+
+	var manifest ociManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid OCI manifest. %w", err)
+	}
+
+	return &manifest, nil
+}
+
+func selectOciLayer(manifest *ociManifest, name string) (*ociDescriptor, error) {
+	for i, layer := range manifest.Layers {
+		if layer.Annotations[ociTitleAnnotation] == path.Base(name) {
+			return &manifest.Layers[i], nil
+		}
+	}
+
+	if len(manifest.Layers) == 1 {
+		return &manifest.Layers[0], nil
+	}
+
+	return nil, fmt.Errorf("OCI artifact does not contain a layer titled %s", path.Base(name))
+}
+
+// downloadOci downloads the layer of an OCI artifact matching name and
+// verifies it against the layer digest.
+func (updater *Updater) downloadOci(ctx context.Context, alternate *url.URL, name string, destination string) error {
+	reference, err := parseOciReference(alternate)
+	if err != nil {
+		return err
+	}
+
+	client := &ociClient{
+		reference:      reference,
+		httpClient:     updater.httpClient(),
+		prepareRequest: updater.prepareRequest,
+		credentials:    updater.config.RegistryAuth[reference.registry],
+	}
+
+	manifest, err := client.manifest(ctx)
+	if err != nil {
+		return err
+	}
+
+	layer, err := selectOciLayer(manifest, name)
+	if err != nil {
+		return err
+	}
+
+	expected, err := parseDigest(layer.Digest)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.get(ctx, "blobs/"+layer.Digest, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	size := resp.ContentLength
+	if size < 0 {
+		size = layer.Size
+	}
+	body, err := updater.limitDownload(name, 0, size, resp.Body)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), updater.newProgress(resp.ContentLength).download(updater, name).wrap(body))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != expected {
+		return fmt.Errorf("%w. Downloaded layer does not match digest %s", ErrChecksumMismatch, layer.Digest)
+	}
+
+	return nil
+}
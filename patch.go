@@ -0,0 +1,207 @@
+package updater
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var errInvalidPatch = errors.New("Invalid bsdiff patch")
+
+// patchName renders the name of the patch from the current version, or
+// returns the empty string when the manifest has no patch for it.
+func (updater *Updater) patchName(info *downloadInfo) (string, error) {
+	patch := info.manifest.Patches[strings.TrimSpace(updater.config.CurrentVersion)]
+	if strings.TrimSpace(patch) == "" {
+		return "", nil
+	}
+
+	variables, err := info.manifest.platformVariables(runtime.GOOS, runtime.GOARCH, detectLibc(), detectCPULevel())
+	if err != nil {
+		return "", err
+	}
+
+	return renderTemplate("PatchTemplate", patch, variables)
+}
+
+// stagePatch downloads the patch from the current version and applies it to
+// the installed binary. The patched binary must match the manifest checksum
+// of the binary, patches are not used otherwise. Patches are never in the
+// AllowedChecksums, which only list archives/binaries, so they are skipped
+// when it is set.
+func (updater *Updater) stagePatch(ctx context.Context, info *downloadInfo) (*stagedUpdate, error) {
+	if info.migrationName != "" || (info.archiveName != "" && updater.config.InstallDir != "") || len(updater.config.AllowedChecksums) > 0 {
+		return nil, nil
+	}
+
+	resultName := uncompressedName(info.binaryName)
+	if _, ok := info.manifest.Checksums[resultName]; !ok {
+		return nil, nil
+	}
+
+	name, err := updater.patchName(info)
+	if err != nil || name == "" {
+		return nil, err
+	}
+
+	oldPath, err := updater.targetPath(info.destName)
+	if err != nil {
+		return nil, err
+	}
+	old, err := os.Open(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	defer old.Close()
+
+	patchPath, err := updater.download(ctx, info, func() string { return name })
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(patchPath)
+
+	patch, err := os.Open(patchPath)
+	if err != nil {
+		return nil, err
+	}
+	defer patch.Close()
+
+	stagedPath := filepath.Join(filepath.Dir(patchPath), uuid.NewString())
+	file, err := os.Create(stagedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	err = bspatch(old, patch, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = verifyManifestChecksum(info.manifest, resultName, stagedPath)
+	}
+	if err != nil {
+		os.Remove(stagedPath)
+		return nil, fmt.Errorf("Error applying patch %s. %w", name, err)
+	}
+
+	return &stagedUpdate{path: stagedPath, binaryPath: stagedPath}, nil
+}
+
+// bspatch applies a patch in the BSDIFF40 format produced by bsdiff to old,
+// writing the new file to writer. Both files are read in bounded chunks.
+func bspatch(old *os.File, patch *os.File, writer io.Writer) error {
+	oldInfo, err := old.Stat()
+	if err != nil {
+		return err
+	}
+	patchInfo, err := patch.Stat()
+	if err != nil {
+		return err
+	}
+	oldSize, patchSize := oldInfo.Size(), patchInfo.Size()
+
+	var header [32]byte
+	_, err = patch.ReadAt(header[:], 0)
+	if err != nil || string(header[:8]) != "BSDIFF40" {
+		return fmt.Errorf("%w. Missing BSDIFF40 header", errInvalidPatch)
+	}
+
+	ctrlLen := offtin(header[8:16])
+	diffLen := offtin(header[16:24])
+	newSize := offtin(header[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || ctrlLen > patchSize-32 || diffLen > patchSize-32-ctrlLen {
+		return fmt.Errorf("%w. Corrupt header", errInvalidPatch)
+	}
+
+	ctrlReader := bzip2.NewReader(io.NewSectionReader(patch, 32, ctrlLen))
+	diffReader := bzip2.NewReader(io.NewSectionReader(patch, 32+ctrlLen, diffLen))
+	extraReader := bzip2.NewReader(io.NewSectionReader(patch, 32+ctrlLen+diffLen, patchSize-32-ctrlLen-diffLen))
+
+	var ctrl [24]byte
+	buf := make([]byte, 32*1024)
+	oldBuf := make([]byte, len(buf))
+	oldPos, newPos := int64(0), int64(0)
+	for newPos < newSize {
+		_, err := io.ReadFull(ctrlReader, ctrl[:])
+		if err != nil {
+			return fmt.Errorf("%w. %w", errInvalidPatch, err)
+		}
+		diffSize, extraSize, seek := offtin(ctrl[0:8]), offtin(ctrl[8:16]), offtin(ctrl[16:24])
+		if diffSize < 0 || extraSize < 0 || diffSize > newSize-newPos || extraSize > newSize-newPos-diffSize {
+			return fmt.Errorf("%w. Corrupt control block", errInvalidPatch)
+		}
+
+		// The diff block holds bytes to add to the old file, which may only
+		// partially overlap the range being patched.
+		for diffSize > 0 {
+			n := int64(len(buf))
+			if n > diffSize {
+				n = diffSize
+			}
+			_, err = io.ReadFull(diffReader, buf[:n])
+			if err != nil {
+				return fmt.Errorf("%w. %w", errInvalidPatch, err)
+			}
+
+			start, end := oldPos, oldPos+n
+			if start < 0 {
+				start = 0
+			}
+			if end > oldSize {
+				end = oldSize
+			}
+			if start < end {
+				_, err = old.ReadAt(oldBuf[:end-start], start)
+				if err != nil {
+					return err
+				}
+				for i := start; i < end; i++ {
+					buf[i-oldPos] += oldBuf[i-start]
+				}
+			}
+
+			_, err = writer.Write(buf[:n])
+			if err != nil {
+				return err
+			}
+			oldPos += n
+			newPos += n
+			diffSize -= n
+		}
+
+		// The extra block holds new bytes copied as is.
+		_, err = io.CopyN(writer, extraReader, extraSize)
+		if err != nil {
+			return fmt.Errorf("%w. %w", errInvalidPatch, err)
+		}
+		newPos += extraSize
+		oldPos += seek
+	}
+
+	return nil
+}
+
+// offtin decodes the sign-magnitude little-endian integers of bsdiff.
+func offtin(buf []byte) int64 {
+	value := int64(binary.LittleEndian.Uint64(buf) &^ (1 << 63))
+	if buf[7]&0x80 != 0 {
+		return -value
+	}
+
+	return value
+}
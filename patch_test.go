@@ -0,0 +1,139 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures in testdata/bsdiff patch old into new with three control
+// entries, including a diff block past the end of old and a negative seek.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "bsdiff", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func writeTemp(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
+
+// patchHeader returns a BSDIFF40 header with the given lengths, encoded like
+// offtin decodes them.
+func patchHeader(ctrlLen int64, diffLen int64, newSize int64) []byte {
+	header := []byte("BSDIFF40")
+	for _, value := range []int64{ctrlLen, diffLen, newSize} {
+		var buf [8]byte
+		if value < 0 {
+			binary.LittleEndian.PutUint64(buf[:], uint64(-value))
+			buf[7] |= 0x80
+		} else {
+			binary.LittleEndian.PutUint64(buf[:], uint64(value))
+		}
+		header = append(header, buf[:]...)
+	}
+
+	return header
+}
+
+func TestBspatch(t *testing.T) {
+	old := readFixture(t, "old")
+	patch := readFixture(t, "patch")
+	newSize := int64(len(readFixture(t, "new")))
+
+	tests := []struct {
+		name    string
+		patch   []byte
+		want    []byte
+		wantErr error
+	}{
+		{
+			name:  "fixture",
+			patch: patch,
+			want:  readFixture(t, "new"),
+		},
+		{
+			name:    "empty",
+			patch:   nil,
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "truncated header",
+			patch:   patch[:20],
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "wrong magic",
+			patch:   append([]byte("BSDIFF41"), patch[8:]...),
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "negative ctrl length",
+			patch:   append(patchHeader(-1, 0, newSize), patch[32:]...),
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "oversized ctrl length",
+			patch:   append(patchHeader(int64(len(patch)), 0, newSize), patch[32:]...),
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "negative new size",
+			patch:   append(patchHeader(0, 0, -1), patch[32:]...),
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "negative ctrl value",
+			patch:   readFixture(t, "negative-ctrl.patch"),
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "oversized ctrl value",
+			patch:   readFixture(t, "oversized-ctrl.patch"),
+			wantErr: errInvalidPatch,
+		},
+		{
+			name:    "corrupt bzip2 stream",
+			patch:   append(patchHeader(16, 0, newSize), bytes.Repeat([]byte{0xff}, 16)...),
+			wantErr: errInvalidPatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := bspatch(writeTemp(t, old), writeTemp(t, test.patch), &out)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("bspatch() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bspatch() error = %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), test.want) {
+				t.Errorf("bspatch() = %q, want %q", out.Bytes(), test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrVersionConstraint = errors.New("Version is outside of the VersionConstraint")
+
+// checkVersionConstraint fails when version is outside of the
+// VersionConstraint.
+func (updater *Updater) checkVersionConstraint(version string) error {
+	if strings.TrimSpace(updater.config.VersionConstraint) == "" {
+		return nil
+	}
+
+	constraint, err := parseConstraint(updater.config.VersionConstraint)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseVersion(version)
+	if err != nil || !constraint.matches(parsed) {
+		return fmt.Errorf("%w. %s does not match %q", ErrVersionConstraint, version, updater.config.VersionConstraint)
+	}
+
+	return nil
+}
+
+// constrainedVersion returns the version to update to within the
+// VersionConstraint: the latest version when it matches, otherwise the newest
+// matching version of the manifest Versions.
+func (updater *Updater) constrainedVersion(manifest *UpdaterManifest) (string, error) {
+	latest := strings.TrimSpace(manifest.Version)
+	err := updater.checkVersionConstraint(latest)
+	if err == nil || !errors.Is(err, ErrVersionConstraint) {
+		return latest, err
+	}
+
+	selected := ""
+	var selectedVersion *semVersion
+	for version := range manifest.Versions {
+		parsed, parseErr := parseVersion(version)
+		if parseErr != nil || updater.checkVersionConstraint(version) != nil {
+			continue
+		}
+		if selectedVersion == nil || parsed.compare(selectedVersion) > 0 {
+			selected, selectedVersion = version, parsed
+		}
+	}
+
+	if selected == "" {
+		return "", fmt.Errorf("%w. The latest version %s does not match %q and no other version in the manifest does", ErrVersionConstraint, latest, updater.config.VersionConstraint)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,105 @@
+package updater
+
+import (
+	"context"
+	"strings"
+)
+
+// UpdatePlan describes what Update would do, resolved from the manifest
+// without downloading or installing anything.
+type UpdatePlan struct {
+	CurrentVersion string
+	Version        string
+	// UpdateAvailable reports whether Version is an update over the current
+	// version, as CheckForAvailableUpdate would, before rollouts and skipped
+	// versions. Update installs Version regardless.
+	UpdateAvailable bool
+	Channel         string
+	// ArchiveName is empty when the binary is downloaded directly.
+	ArchiveName string
+	BinaryName  string
+	// DestName is the name of the binary in the InstallDir.
+	DestName      string
+	MigrationName string
+	// DownloadName is the name of the archive, or binary, to download from
+	// DownloadUrls, tried in order. A download from the Source is not listed.
+	DownloadName string
+	DownloadUrls []string
+	Checksum     string
+	// Size is the manifest size of the download, or -1 when unknown.
+	Size int64
+	// TargetPath is the binary replaced by the update, or the InstallDir when
+	// the whole archive is installed.
+	TargetPath        string
+	RequiresElevation bool
+	Manifest          *UpdaterManifest
+}
+
+// Plan resolves the latest version like Update without touching the
+// filesystem, e.g., to print or log what an update would do. Besides
+// fetching the manifest, Plan only reads the state persisted at the
+// StorePath, and the manifest cache with CacheManifest.
+func (updater *Updater) Plan() (*UpdatePlan, error) {
+	return updater.PlanContext(context.Background())
+}
+
+func (updater *Updater) PlanContext(ctx context.Context) (*UpdatePlan, error) {
+	info, err := updater.resolveDownloadInfo(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	currentVersion := strings.TrimSpace(updater.config.CurrentVersion)
+	version := strings.TrimSpace(info.manifest.Version)
+	plan := &UpdatePlan{
+		CurrentVersion:  currentVersion,
+		Version:         version,
+		UpdateAvailable: updater.isNewer(currentVersion, version),
+		Channel:         updater.Channel(),
+		ArchiveName:     info.archiveName,
+		BinaryName:      info.binaryName,
+		DestName:        info.destName,
+		MigrationName:   info.migrationName,
+		DownloadName:    info.archiveName,
+		Size:            -1,
+		Manifest:        info.manifest,
+	}
+	if plan.DownloadName == "" {
+		plan.DownloadName = info.binaryName
+	}
+
+	candidates := 1 + len(info.manifest.Urls[plan.DownloadName])
+	for candidate := 0; candidate < candidates; candidate++ {
+		if candidate == 0 && updater.config.Source != nil {
+			if _, ok := info.manifest.assetUrls[plan.DownloadName]; !ok {
+				continue
+			}
+		}
+		downloadUrl, err := updater.candidateUrl(info, plan.DownloadName, candidate)
+		if err != nil {
+			return nil, err
+		}
+		plan.DownloadUrls = append(plan.DownloadUrls, downloadUrl)
+	}
+
+	plan.Checksum = info.manifest.Checksums[plan.DownloadName]
+	if size, ok := info.manifest.Sizes[plan.DownloadName]; ok {
+		plan.Size = size
+	}
+
+	if info.archiveName != "" && updater.config.InstallDir != "" {
+		plan.TargetPath = updater.config.InstallDir
+	} else {
+		plan.TargetPath, err = updater.targetPath(info.destName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan.RequiresElevation, err = updater.requiresElevation(dirAccessible)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,31 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInsufficientPower = errors.New("Insufficient battery power to install the update")
+
+type powerStatus struct {
+	onBattery bool
+	percent   int
+}
+
+func (updater *Updater) checkPower() error {
+	minPercent := updater.config.MinBatteryPercent
+	if minPercent <= 0 {
+		return nil
+	}
+
+	status, ok := readPowerStatus()
+	if !ok || !status.onBattery {
+		return nil
+	}
+
+	if status.percent < minPercent {
+		return fmt.Errorf("%w. Battery is at %d%% but %d%% is required", ErrInsufficientPower, status.percent, minPercent)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package updater
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+func readPowerStatus() (powerStatus, bool) {
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return powerStatus{}, false
+	}
+
+	text := string(output)
+	match := pmsetPercent.FindStringSubmatch(text)
+	if match == nil {
+		return powerStatus{}, false
+	}
+
+	percent, err := strconv.Atoi(match[1])
+	if err != nil {
+		return powerStatus{}, false
+	}
+
+	return powerStatus{
+		onBattery: strings.Contains(text, "'Battery Power'"),
+		percent:   percent,
+	}, true
+}
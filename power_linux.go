@@ -0,0 +1,55 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func readPowerSupplyFile(dir string, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+func readPowerStatus() (powerStatus, bool) {
+	supplies, err := filepath.Glob("/sys/class/power_supply/*")
+	if err != nil {
+		return powerStatus{}, false
+	}
+
+	onAC := false
+	percent := -1
+	for _, supply := range supplies {
+		switch readPowerSupplyFile(supply, "type") {
+		case "Mains", "USB":
+			if readPowerSupplyFile(supply, "online") == "1" {
+				onAC = true
+			}
+		case "Battery":
+			if readPowerSupplyFile(supply, "scope") == "Device" {
+				continue
+			}
+			capacity, err := strconv.Atoi(readPowerSupplyFile(supply, "capacity"))
+			if err != nil {
+				continue
+			}
+			if percent < 0 || capacity < percent {
+				percent = capacity
+			}
+			if readPowerSupplyFile(supply, "status") == "Charging" {
+				onAC = true
+			}
+		}
+	}
+
+	if percent < 0 {
+		return powerStatus{}, false
+	}
+
+	return powerStatus{onBattery: !onAC, percent: percent}, true
+}
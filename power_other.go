@@ -0,0 +1,7 @@
+//go:build !linux && !windows && !darwin
+
+package updater
+
+func readPowerStatus() (powerStatus, bool) {
+	return powerStatus{}, false
+}
@@ -0,0 +1,37 @@
+package updater
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+var procGetSystemPowerStatus = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetSystemPowerStatus")
+
+func readPowerStatus() (powerStatus, bool) {
+	var status systemPowerStatus
+	result, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status)))
+	if result == 0 {
+		return powerStatus{}, false
+	}
+
+	const noSystemBattery = 128
+	const unknown = 255
+	if status.BatteryFlag&noSystemBattery != 0 || status.ACLineStatus == unknown || status.BatteryLifePercent == unknown {
+		return powerStatus{}, false
+	}
+
+	return powerStatus{
+		onBattery: status.ACLineStatus == 0,
+		percent:   int(status.BatteryLifePercent),
+	}, true
+}
@@ -0,0 +1,57 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrInsufficientPermissions = errors.New("Insufficient permissions to install the update")
+
+// installTarget is the binary, or the InstallDir, replaced by updates.
+func (updater *Updater) installTarget() (string, error) {
+	if updater.config.TargetPath != "" || updater.config.InstallDir == "" {
+		return updater.targetPath("")
+	}
+
+	return filepath.Clean(updater.config.InstallDir), nil
+}
+
+// checkPermissions verifies that the install location can be written before
+// anything is downloaded. Locations that need elevation are only refused
+// with ElevationFail, other strategies handle them when installing.
+func (updater *Updater) checkPermissions() error {
+	if updater.config.Elevation != "" && updater.config.Elevation != ElevationFail {
+		return nil
+	}
+
+	target, err := updater.installTarget()
+	if err != nil {
+		return err
+	}
+	if protectedPath(target) {
+		return fmt.Errorf("%w. %s is under Program Files and the process is not elevated", ErrInsufficientPermissions, target)
+	}
+
+	dir, err := existingDir(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, ".updater-")
+	if err == nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil
+	}
+
+	switch {
+	case readOnlyFilesystem(err):
+		return fmt.Errorf("%w. %s is on a read-only filesystem", ErrInsufficientPermissions, dir)
+	case ownedByRoot(dir):
+		return fmt.Errorf("%w. %s is owned by root and the process is not running as root", ErrInsufficientPermissions, dir)
+	default:
+		return fmt.Errorf("%w. %s is not writable. %w", ErrInsufficientPermissions, dir, err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd
+
+package updater
+
+import "os"
+
+func readOnlyFilesystem(err error) bool {
+	return false
+}
+
+func ownedByRoot(path string) bool {
+	return false
+}
+
+func ownedByAnotherUser(info os.FileInfo) bool {
+	return false
+}
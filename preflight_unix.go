@@ -0,0 +1,30 @@
+//go:build linux || darwin || freebsd
+
+package updater
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func readOnlyFilesystem(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
+
+func ownedByRoot(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || os.Geteuid() == 0 {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == 0
+}
+
+// ownedByAnotherUser reports whether the file is owned by a user other than
+// the current one.
+func ownedByAnotherUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid != uint32(os.Geteuid())
+}
@@ -0,0 +1,102 @@
+package updater
+
+import (
+	"archive/zip"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// ProgressFunc receives the bytes transferred so far and the total, or -1 when
+// the total is unknown.
+type ProgressFunc func(bytesDownloaded int64, totalBytes int64)
+
+// progressReader reports reads to the configured Progress. A single
+// progressReader may wrap several readers in turn, e.g., the entries of a zip
+// archive, reporting their combined progress.
+type progressReader struct {
+	reader   io.Reader
+	progress ProgressFunc
+	done     int64
+	total    int64
+	// name is the download tracked by download, logged to logger and emitted
+	// to onEvent.
+	name    string
+	logger  *slog.Logger
+	nextLog int64
+	onEvent func(event Event)
+	metrics Metrics
+}
+
+func (updater *Updater) newProgress(total int64) *progressReader {
+	return updater.resumeProgress(0, total)
+}
+
+// resumeProgress reports progress starting from done bytes, e.g., when a
+// download is resumed.
+func (updater *Updater) resumeProgress(done int64, total int64) *progressReader {
+	if updater.config.Progress != nil {
+		updater.config.Progress(done, total)
+	}
+
+	return &progressReader{progress: updater.config.Progress, done: done, total: total}
+}
+
+// fileProgress reports progress relative to the size of the file at path.
+func (updater *Updater) fileProgress(path string) *progressReader {
+	total := int64(-1)
+	info, err := os.Stat(path)
+	if err == nil {
+		total = info.Size()
+	}
+
+	return updater.newProgress(total)
+}
+
+// zipProgress reports progress relative to the uncompressed size of the
+// regular files in the archive.
+func (updater *Updater) zipProgress(files []*zip.File) *progressReader {
+	total := int64(0)
+	for _, f := range files {
+		if f.FileInfo().Mode().IsRegular() {
+			total += int64(f.UncompressedSize64)
+		}
+	}
+
+	return updater.newProgress(total)
+}
+
+// download reports the progress as the download of name to the Logger,
+// OnEvent and Metrics as well.
+func (reader *progressReader) download(updater *Updater, name string) *progressReader {
+	reader.name = name
+	reader.onEvent = updater.config.OnEvent
+	reader.metrics = updater.config.Metrics
+	reader.logTo(updater.logger())
+
+	return reader
+}
+
+func (reader *progressReader) wrap(wrapped io.Reader) io.Reader {
+	reader.reader = wrapped
+	return reader
+}
+
+func (reader *progressReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	if n > 0 {
+		reader.done += int64(n)
+		if reader.progress != nil {
+			reader.progress(reader.done, reader.total)
+		}
+		reader.logProgress()
+		if reader.metrics != nil {
+			reader.metrics.DownloadedBytes(int64(n))
+		}
+		if reader.onEvent != nil {
+			reader.onEvent(Event{Type: EventDownloadProgress, Name: reader.name, BytesDownloaded: reader.done, TotalBytes: reader.total})
+		}
+	}
+
+	return n, err
+}
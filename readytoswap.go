@@ -0,0 +1,46 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotReadyToSwap = errors.New("Application did not become ready to swap")
+
+func (updater *Updater) waitReadyToSwap(ctx context.Context) error {
+	readyToSwap := updater.config.ReadyToSwap
+	if readyToSwap == nil {
+		return nil
+	}
+
+	timeout := updater.config.ReadyToSwapTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	interval := updater.config.ReadyToSwapInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ready, err := readyToSwap()
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("%w within %s", ErrNotReadyToSwap, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
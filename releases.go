@@ -0,0 +1,193 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrVersionNotFound = errors.New("Version not found in the manifest")
+
+type UpdaterRelease struct {
+	Version string `json:"version"`
+}
+
+func (updater *Updater) VersionsBetween() ([]string, error) {
+	return updater.VersionsBetweenContext(context.Background())
+}
+
+func (updater *Updater) VersionsBetweenContext(ctx context.Context) ([]string, error) {
+	current, err := parseVersion(updater.config.CurrentVersion)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid current version. %w", err)
+	}
+
+	manifest, err := updater.GetManifestContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := parseVersion(manifest.Version)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Invalid manifest version. %w", ErrManifestInvalid, err)
+	}
+
+	type release struct {
+		name    string
+		version *semVersion
+	}
+
+	var releases []release
+	seen := make(map[string]bool)
+	candidates := append([]string{manifest.Version}, releaseVersions(manifest)...)
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		version, err := parseVersion(candidate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid release version. %w", err)
+		}
+		if version.compare(current) <= 0 || version.compare(latest) > 0 || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		releases = append(releases, release{name: candidate, version: version})
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].version.compare(releases[j].version) < 0
+	})
+
+	versions := make([]string, len(releases))
+	for i, release := range releases {
+		versions[i] = release.name
+	}
+
+	return versions, nil
+}
+
+func releaseVersions(manifest *UpdaterManifest) []string {
+	versions := make([]string, 0, len(manifest.Releases)+len(manifest.Versions))
+	for _, release := range manifest.Releases {
+		versions = append(versions, release.Version)
+	}
+	for version := range manifest.Versions {
+		versions = append(versions, version)
+	}
+
+	return versions
+}
+
+// versionManifest returns the manifest of a version other than the latest.
+// Fields describing the latest release are not inherited by other versions,
+// the Versions entry overrides the rest. Versions that are not listed are
+// only resolved when the download name depends on the version.
+func (manifest *UpdaterManifest) versionManifest(version string) (*UpdaterManifest, error) {
+	if version == strings.TrimSpace(manifest.Version) {
+		return manifest, nil
+	}
+
+	base := *manifest
+	base.Version = version
+	base.Checksums = nil
+	base.Sizes = nil
+	base.Jws = nil
+	base.BuildTime = time.Time{}
+	base.Patches = nil
+	base.Rollout = nil
+	base.ReleaseNotes = ""
+	base.PublishedAt = time.Time{}
+	base.Url = ""
+
+	override := manifest.Versions[version]
+	if override == nil {
+		name := manifest.Archive
+		if strings.TrimSpace(name) == "" {
+			name = manifest.Binary
+		}
+		if !strings.Contains(name, ".Version") {
+			return nil, fmt.Errorf("%w. %s is not listed in the manifest versions", ErrVersionNotFound, version)
+		}
+		return &base, nil
+	}
+
+	resolved := base.withOverride(override)
+	resolved.Version = version
+	resolved.Versions = manifest.Versions
+
+	return resolved, nil
+}
+
+// AvailableVersion is a version published in the manifest. Channel is the
+// empty string for the default channel.
+type AvailableVersion struct {
+	Version     string
+	Channel     string
+	PublishedAt time.Time
+}
+
+// ListAvailableVersions returns the versions published in the manifest,
+// across all channels, newest first. Each version is listed once per channel
+// publishing it.
+func (updater *Updater) ListAvailableVersions() ([]AvailableVersion, error) {
+	return updater.ListAvailableVersionsContext(context.Background())
+}
+
+func (updater *Updater) ListAvailableVersionsContext(ctx context.Context) ([]AvailableVersion, error) {
+	var manifest *UpdaterManifest
+	var err error
+	if updater.config.GitHubSource != nil {
+		manifest, err = updater.config.GitHubSource.manifest(ctx, updater)
+	} else {
+		manifest, err = updater.getManifest(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []AvailableVersion
+	seen := make(map[AvailableVersion]bool)
+	add := func(version string, channel string, publishedAt time.Time) {
+		available := AvailableVersion{Version: strings.TrimSpace(version), Channel: channel}
+		if available.Version == "" || seen[available] {
+			return
+		}
+		seen[available] = true
+		available.PublishedAt = publishedAt
+		versions = append(versions, available)
+	}
+	addManifest := func(manifest *UpdaterManifest, channel string) {
+		add(manifest.Version, channel, manifest.PublishedAt)
+		for version, release := range manifest.Versions {
+			if release != nil {
+				add(version, channel, release.PublishedAt)
+			}
+		}
+		for _, release := range manifest.Releases {
+			add(release.Version, channel, time.Time{})
+		}
+	}
+
+	addManifest(manifest, "")
+	for channel, channelManifest := range manifest.Channels {
+		if channelManifest != nil {
+			addManifest(channelManifest, channel)
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		iVersion, iErr := parseVersion(versions[i].Version)
+		jVersion, jErr := parseVersion(versions[j].Version)
+		if iErr == nil && jErr == nil && iVersion.compare(jVersion) != 0 {
+			return iVersion.compare(jVersion) > 0
+		}
+		if versions[i].Version != versions[j].Version {
+			return versions[i].Version > versions[j].Version
+		}
+		return versions[i].Channel < versions[j].Channel
+	})
+
+	return versions, nil
+}
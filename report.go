@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type UpdateReport struct {
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion"`
+	Outcome     string `json:"outcome"`
+	ErrorClass  string `json:"errorClass,omitempty"`
+	DurationMs  int64  `json:"durationMs"`
+}
+
+var errorClasses = []struct {
+	err   error
+	class string
+}{
+	{ErrNotEntitled, "not_entitled"},
+	{ErrProductMismatch, "product_mismatch"},
+	{ErrOutsideMaintenanceWindow, "outside_maintenance_window"},
+	{ErrNotReadyToSwap, "not_ready_to_swap"},
+	{ErrInsufficientPower, "insufficient_power"},
+	{ErrKeyNotTrusted, "key_not_trusted"},
+	{ErrLinkPolicyViolation, "link_policy_violation"},
+	{ErrBuildTooOld, "build_too_old"},
+	{ErrSmokeTestFailed, "smoke_test_failed"},
+	{ErrChecksumNotAllowed, "checksum_not_allowed"},
+	{ErrChecksumMismatch, "checksum_mismatch"},
+	{ErrUpdateInProgress, "update_in_progress"},
+	{ErrSignatureThreshold, "signature_threshold"},
+	{ErrNotInTransparencyLog, "not_in_transparency_log"},
+	{ErrMetadataMismatch, "metadata_mismatch"},
+	{ErrMigrationFailed, "migration_failed"},
+	{ErrNoRollback, "no_rollback"},
+	{ErrInsufficientDiskSpace, "insufficient_disk_space"},
+	{ErrArchiveTooLarge, "archive_too_large"},
+	{ErrDownloadTooLarge, "download_too_large"},
+	{ErrVersionNotFound, "version_not_found"},
+	{ErrDowngrade, "downgrade"},
+	{ErrVersionConstraint, "version_constraint"},
+	{ErrHookFailed, "hook_failed"},
+	{ErrHealthCheckFailed, "health_check_failed"},
+	{ErrElevationRequired, "elevation_required"},
+	{ErrInsufficientPermissions, "insufficient_permissions"},
+	{ErrManifestInvalid, "manifest_invalid"},
+	{ErrBinaryNotFoundInArchive, "binary_not_found_in_archive"},
+}
+
+func errorClass(err error) string {
+	for _, known := range errorClasses {
+		if errors.Is(err, known.err) {
+			return known.class
+		}
+	}
+
+	var notSupported *NotSupportedError
+	if errors.As(err, &notSupported) {
+		return "not_supported"
+	}
+
+	var httpStatus *HTTPStatusError
+	if errors.As(err, &httpStatus) {
+		return "http_status"
+	}
+
+	return "error"
+}
+
+func (updater *Updater) newReport(start time.Time, info *downloadInfo, err error) *UpdateReport {
+	report := &UpdateReport{
+		FromVersion: strings.TrimSpace(updater.config.CurrentVersion),
+		Outcome:     "success",
+		DurationMs:  time.Since(start).Milliseconds(),
+	}
+	if info != nil {
+		report.ToVersion = strings.TrimSpace(info.manifest.Version)
+	}
+
+	if err != nil {
+		report.Outcome = "failure"
+		if updater.pending != nil {
+			report.Outcome = "deferred"
+		}
+		report.ErrorClass = errorClass(err)
+	}
+
+	return report
+}
+
+// reportTimeout bounds sending a report, regardless of the Update context.
+const reportTimeout = 30 * time.Second
+
+// report sends the outcome of an update to the ReportEndpoint in the
+// background. Reporting is best effort and never affects the update result.
+// The report outlives the cancellation of ctx, see WaitReports.
+func (updater *Updater) report(ctx context.Context, start time.Time, info *downloadInfo, err error) {
+	endpoint := updater.config.ReportEndpoint
+	if endpoint == "" || validateUrl(endpoint) != nil {
+		return
+	}
+
+	body, marshalErr := json.Marshal(updater.newReport(start, info, err))
+	if marshalErr != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportTimeout)
+	updater.reports.Add(1)
+	go func() {
+		defer updater.reports.Done()
+		defer cancel()
+
+		request, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		request.Header.Set("Content-Type", "application/json")
+
+		resp, err := updater.httpClient().Do(request)
+		if err != nil {
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// WaitReports waits for the reports sent to the ReportEndpoint in the
+// background to be sent, e.g., before a CLI exits after an update. It returns
+// the context error if ctx is done first.
+func (updater *Updater) WaitReports(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		updater.reports.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,44 @@
+package updater
+
+import (
+	"errors"
+	"os"
+)
+
+var ErrNothingToRestart = errors.New("No installed binary to restart")
+
+// restartPath returns the binary installed by the last update, or the target
+// binary when no update was installed by this Updater.
+func (updater *Updater) restartPath() (string, error) {
+	updater.stateMu.Lock()
+	installedPath := updater.installedPath
+	updater.stateMu.Unlock()
+	if installedPath != "" {
+		return installedPath, nil
+	}
+
+	if updater.config.TargetPath == "" && updater.config.InstallDir != "" {
+		return "", ErrNothingToRestart
+	}
+
+	return updater.targetPath("")
+}
+
+// Restart re-executes the installed binary, e.g., after Update, so that the
+// application continues on the new version, with the environment and working
+// directory of the current process. args default to the arguments of the
+// current process. On Unix the current process is replaced and Restart only
+// returns on failure. On Windows, where processes cannot be replaced, the new
+// binary is started and the current process exits.
+func (updater *Updater) Restart(args ...string) error {
+	path, err := updater.restartPath()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 && len(os.Args) > 1 {
+		args = os.Args[1:]
+	}
+
+	return restartProcess(path, args)
+}
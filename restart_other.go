@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package updater
+
+import "runtime"
+
+func restartProcess(path string, args []string) error {
+	return &NotSupportedError{Platform: runtime.GOOS}
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+
+package updater
+
+import (
+	"os"
+	"syscall"
+)
+
+func restartProcess(path string, args []string) error {
+	argv0 := path
+	if len(os.Args) > 0 {
+		argv0 = os.Args[0]
+	}
+
+	return syscall.Exec(path, append([]string{argv0}, args...), os.Environ())
+}
@@ -0,0 +1,29 @@
+package updater
+
+import (
+	"os"
+	"os/exec"
+)
+
+// restartProcess starts the new binary with the standard streams of the
+// current process and exits once it started.
+func restartProcess(path string, args []string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Env = os.Environ()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	dir, err := os.Getwd()
+	if err == nil {
+		cmd.Dir = dir
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	os.Exit(0)
+	return nil
+}
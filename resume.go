@@ -0,0 +1,273 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// partialDownload is a download in progress in a private directory, see
+// partialDir. The validator of the response, its ETag or Last-Modified date,
+// is kept alongside so that the download is only resumed while the file on
+// the server is unchanged.
+type partialDownload struct {
+	path     string
+	metaPath string
+	// tempDir is the directory of the partial download when there is no
+	// private cache directory, removed once the download is done.
+	tempDir string
+	// resumed reports whether the download was reassembled from a range
+	// request, requiring the whole file to be verified.
+	resumed bool
+}
+
+type partialMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func (updater *Updater) partialDownload(info *downloadInfo, name string, candidate int) (*partialDownload, error) {
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n%d", updater.config.BaseUrl, info.manifest.Version, name, candidate)))
+	filename := "updater-" + hex.EncodeToString(key[:16]) + ".partial"
+
+	dir, err := updater.partialDir()
+	if err != nil {
+		// Without a private directory, downloads are only resumed by the
+		// retries of this download.
+		updater.logger().Debug("Partial downloads are not kept", "error", err)
+		tempDir, err := os.MkdirTemp("", "updater-")
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(tempDir, filename)
+		return &partialDownload{path: path, metaPath: path + ".json", tempDir: tempDir}, nil
+	}
+
+	path := filepath.Join(dir, filename)
+	return &partialDownload{path: path, metaPath: path + ".json"}, nil
+}
+
+// partialDir returns the directory keeping partial downloads, "partial" in
+// CacheDir or in the user cache directory. Partial downloads are appended to,
+// so the directory must only be writable by the current user, unlike the
+// shared temp dir.
+func (updater *Updater) partialDir() (string, error) {
+	base := updater.config.CacheDir
+	if base == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(userCacheDir, "updater")
+	}
+
+	dir := filepath.Join(base, "partial")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() || ownedByAnotherUser(info) {
+		return "", fmt.Errorf("%s is not a directory owned by the current user", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		err = os.Chmod(dir, 0700)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// close removes the temp dir of the partial download, if any.
+func (partial *partialDownload) close() {
+	if partial.tempDir != "" {
+		os.RemoveAll(partial.tempDir)
+	}
+}
+
+func (partial *partialDownload) remove() {
+	os.Remove(partial.path)
+	os.Remove(partial.metaPath)
+}
+
+func (partial *partialDownload) finish(destination string) error {
+	os.Remove(partial.metaPath)
+
+	return os.Rename(partial.path, destination)
+}
+
+// validator returns the If-Range validator for resuming the download, or the
+// empty string if the download cannot be resumed. Weak ETags cannot be used
+// for range requests.
+func (partial *partialDownload) validator() string {
+	data, err := os.ReadFile(partial.metaPath)
+	if err != nil {
+		return ""
+	}
+
+	var meta partialMeta
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return ""
+	}
+
+	if meta.ETag != "" && !strings.HasPrefix(meta.ETag, "W/") {
+		return meta.ETag
+	}
+
+	return meta.LastModified
+}
+
+func (partial *partialDownload) writeMeta(resp *http.Response) error {
+	data, err := json.Marshal(partialMeta{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(partial.metaPath, data, 0600)
+}
+
+// contentRangeStart parses the first byte position of a Content-Range header,
+// e.g., "bytes 100-199/200".
+func contentRangeStart(header string) (int64, error) {
+	unit, byteRange, found := strings.Cut(header, " ")
+	if !found || unit != "bytes" {
+		return 0, fmt.Errorf("Invalid Content-Range %q", header)
+	}
+
+	start, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, fmt.Errorf("Invalid Content-Range %q", header)
+	}
+
+	return strconv.ParseInt(start, 10, 64)
+}
+
+// downloadPartial downloads the rest of the partial download, resuming from
+// its current size when possible. interrupted reports whether the download
+// failed while receiving the response body, leaving a partial download to
+// resume.
+func (updater *Updater) downloadPartial(ctx context.Context, info *downloadInfo, name func() string, candidate int, partial *partialDownload) (bool, error) {
+	offset := int64(0)
+	header := http.Header{}
+	stat, err := os.Lstat(partial.path)
+	if err == nil && (!stat.Mode().IsRegular() || ownedByAnotherUser(stat)) {
+		// Never append to a symlink or to a file planted by another user.
+		updater.logger().Warn("Ignoring a partial download that is not a regular file owned by the current user", "path", partial.path)
+		partial.remove()
+		stat, err = nil, os.ErrNotExist
+	}
+	_, hasChecksum := info.manifest.Checksums[name()]
+	if err == nil && stat.Size() > 0 && !hasChecksum {
+		// Without a checksum the resumed download could not be verified as a
+		// whole, start over.
+		partial.remove()
+	} else if err == nil && stat.Size() > 0 {
+		if validator := partial.validator(); validator != "" {
+			offset = stat.Size()
+			header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			header.Set("If-Range", validator)
+		}
+	}
+
+	resp, err := updater.getWithHeader(ctx, updater.downloadUrl(ctx, info, name, candidate), header)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// The partial download does not match the file on the server, start
+		// over.
+		partial.remove()
+		resp.Body.Close()
+		return updater.downloadPartial(ctx, info, name, candidate, partial)
+	}
+
+	if updater.config.VerifyContentDisposition {
+		err = verifyContentDisposition(resp, name())
+		if err != nil {
+			return false, err
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil {
+			partial.remove()
+			return false, fmt.Errorf("Error resuming the download of %s. %w", name(), err)
+		}
+		if start != offset {
+			partial.remove()
+			return false, fmt.Errorf("Error resuming the download of %s. Expected a response starting at byte %d but got %d", name(), offset, start)
+		}
+		flags |= os.O_APPEND
+		partial.resumed = true
+		if total >= 0 {
+			total += offset
+		}
+		updater.logger().Debug("Resuming download", "name", name(), "offset", offset)
+	} else {
+		offset = 0
+		partial.resumed = false
+		flags |= os.O_TRUNC
+		err = partial.writeMeta(resp)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	body, err := updater.limitDownload(name(), offset, total, resp.Body)
+	if err != nil {
+		partial.remove()
+		return false, err
+	}
+
+	if total >= 0 {
+		err = updater.checkDiskSpace(info, name(), total-offset)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	file, err := os.OpenFile(partial.path, flags, 0600)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = io.Copy(file, updater.resumeProgress(offset, total).download(updater, name()).wrap(body))
+	closeErr := file.Close()
+	if errors.Is(err, ErrDownloadTooLarge) {
+		partial.remove()
+		return false, err
+	}
+	if err != nil {
+		return true, err
+	}
+	if closeErr != nil {
+		partial.remove()
+		return false, closeErr
+	}
+
+	return false, nil
+}
@@ -0,0 +1,242 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryBackoff    = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+type RetryPredicate func(resp *http.Response, err error) bool
+
+func DefaultRetryPredicate(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func (updater *Updater) shouldRetry(resp *http.Response, err error) bool {
+	if updater.config.RetryPredicate != nil {
+		return updater.config.RetryPredicate(resp, err)
+	}
+
+	if len(updater.config.RetryStatusCodes) > 0 {
+		if err != nil {
+			return true
+		}
+		for _, statusCode := range updater.config.RetryStatusCodes {
+			if resp.StatusCode == statusCode {
+				return true
+			}
+		}
+		return false
+	}
+
+	return DefaultRetryPredicate(resp, err)
+}
+
+// retryDelay returns the delay before the given retry, exponential backoff
+// with full jitter. A Retry-After header of the failed response is honored up
+// to RetryMaxBackoff.
+func (updater *Updater) retryDelay(retry int, resp *http.Response) time.Duration {
+	backoff := updater.config.RetryBackoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	maxBackoff := updater.config.RetryMaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	delay := maxBackoff
+	if retry < 32 && backoff<<(retry-1) > 0 && backoff<<(retry-1) < maxBackoff {
+		delay = backoff << (retry - 1)
+	}
+	delay = time.Duration(rand.Int63n(int64(delay) + 1))
+
+	if resp != nil {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if retryAfter > maxBackoff {
+			retryAfter = maxBackoff
+		}
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	return delay
+}
+
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(header)
+	if err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(header)
+	if err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// sleep waits for the delay unless the context is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (updater *Updater) httpClient() *http.Client {
+	if updater.config.HTTPClient != nil {
+		return updater.config.HTTPClient
+	}
+
+	return &http.Client{Transport: updater.config.Transport}
+}
+
+// BasicAuth holds the credentials for HTTP basic authentication.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// prepareRequest authenticates a request. Credentials are only sent to the
+// host of the BaseUrl, never to alternate urls on other hosts.
+func (updater *Updater) prepareRequest(request *http.Request) {
+	if updater.config.GitHubSource != nil {
+		updater.config.GitHubSource.prepareRequest(request)
+	}
+
+	baseUrl, err := url.Parse(updater.config.BaseUrl)
+	if err == nil && baseUrl.Host != "" && request.URL.Host == baseUrl.Host {
+		if updater.config.BearerToken != "" {
+			request.Header.Set("Authorization", "Bearer "+updater.config.BearerToken)
+		}
+		if updater.config.BasicAuth != nil {
+			request.SetBasicAuth(updater.config.BasicAuth.Username, updater.config.BasicAuth.Password)
+		}
+	}
+
+	if updater.config.RequestHook != nil {
+		updater.config.RequestHook(request)
+	}
+}
+
+// get requests the url returned by resolve, retrying failed attempts that the
+// retry predicate classifies as retryable. resolve is called once per attempt
+// so that callers can mint a fresh url between attempts.
+func (updater *Updater) get(ctx context.Context, resolve func(attempt int) (string, error)) (*http.Response, error) {
+	return updater.getWithHeader(ctx, resolve, nil)
+}
+
+// getWithHeader is get with additional request headers. Requests with a Range
+// header also succeed with 206 Partial Content and 416 Range Not Satisfiable
+// responses, conditional requests with 304 Not Modified responses.
+func (updater *Updater) getWithHeader(ctx context.Context, resolve func(attempt int) (string, error), header http.Header) (*http.Response, error) {
+	ranged := header.Get("Range") != ""
+	conditional := header.Get("If-None-Match") != "" || header.Get("If-Modified-Since") != ""
+	var lastErr error
+
+	for attempt := 0; attempt <= updater.config.MaxRetries; attempt++ {
+		requestUrl, err := resolve(attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateUrl(requestUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			request.Header[key] = values
+		}
+		updater.prepareRequest(request)
+
+		resp, err := updater.httpClient().Do(request)
+		if err == nil && resp.StatusCode == 200 {
+			return resp, nil
+		}
+		if err == nil && ranged && (resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable) {
+			return resp, nil
+		}
+		if err == nil && conditional && resp.StatusCode == http.StatusNotModified {
+			return resp, nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("Error downloading %s. %w", redactUrlString(requestUrl), httpStatusError(resp))
+		}
+
+		retry := updater.shouldRetry(resp, err)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if !retry || attempt == updater.config.MaxRetries {
+			break
+		}
+
+		delay := updater.retryDelay(attempt+1, resp)
+		updater.logger().Debug("Retrying request", "url", redactUrlString(requestUrl), "attempt", attempt+1, "delay", delay, "error", lastErr)
+		err = sleep(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, lastErr
+}
+
+func (updater *Updater) fetchUrl(ctx context.Context, requestUrl string) ([]byte, error) {
+	resp, err := updater.get(ctx, func(attempt int) (string, error) {
+		return requestUrl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return updater.readSidecar(requestUrl, resp.ContentLength, resp.Body)
+}
+
+// fetch downloads a file hosted at the BaseUrl, or from the Source.
+func (updater *Updater) fetch(ctx context.Context, name string) ([]byte, error) {
+	if updater.config.Source != nil {
+		return updater.readAsset(ctx, name)
+	}
+
+	requestUrl, err := joinUrl(updater.config.BaseUrl, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return updater.fetchUrl(ctx, requestUrl)
+}
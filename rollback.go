@@ -0,0 +1,192 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoRollback = errors.New("No previous version to roll back to")
+
+// rollbackRecord is persisted to RollbackPath after an update is installed and
+// locates the backup of the previous version.
+type rollbackRecord struct {
+	Target          string    `json:"target"`
+	Backup          string    `json:"backup"`
+	Dir             bool      `json:"dir,omitempty"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previousVersion"`
+	InstalledAt     time.Time `json:"installedAt"`
+}
+
+func (updater *Updater) readRollbackRecord() (*rollbackRecord, error) {
+	data, err := os.ReadFile(updater.config.RollbackPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record rollbackRecord
+	err = json.Unmarshal(data, &record)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rollback record %s. %w", updater.config.RollbackPath, err)
+	}
+
+	return &record, nil
+}
+
+func (updater *Updater) writeRollbackRecord(record *rollbackRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(updater.config.RollbackPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	tempPath := updater.config.RollbackPath + "." + uuid.NewString()
+	err = os.WriteFile(tempPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tempPath, updater.config.RollbackPath)
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
+
+func removeBackup(path string, dir bool) {
+	if dir {
+		os.RemoveAll(path)
+	} else {
+		os.Remove(path)
+	}
+}
+
+// keepBackup records the backup of the previous version in place of
+// discarding it. Only the most recent backup is kept. Failing to record the
+// backup only means the update cannot be rolled back.
+func (updater *Updater) keepBackup(info *downloadInfo, installed *installation) {
+	previous, _ := updater.readRollbackRecord()
+	if previous != nil && previous.Backup != installed.backupPath && !updater.isRetained(previous.Backup) {
+		removeBackup(previous.Backup, previous.Dir)
+	}
+
+	if installed.backupPath == "" {
+		os.Remove(updater.config.RollbackPath)
+		return
+	}
+
+	err := updater.writeRollbackRecord(&rollbackRecord{
+		Target:          installed.target,
+		Backup:          installed.backupPath,
+		Dir:             installed.dir,
+		Version:         strings.TrimSpace(info.manifest.Version),
+		PreviousVersion: strings.TrimSpace(updater.config.CurrentVersion),
+		InstalledAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		os.Remove(updater.config.RollbackPath)
+		updater.discardBackup(installed)
+	}
+}
+
+// Rollback restores the version replaced by the last update, e.g., when the
+// new version fails its startup checks. Requires RollbackPath.
+func (updater *Updater) Rollback() error {
+	if updater.config.RollbackPath == "" {
+		return fmt.Errorf("%w. RollbackPath is not set", ErrNoRollback)
+	}
+
+	if !updater.updateMu.TryLock() {
+		return ErrUpdateInProgress
+	}
+	defer updater.updateMu.Unlock()
+
+	lock, err := updater.lockUpdate()
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	record, err := updater.readRollbackRecord()
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return ErrNoRollback
+	}
+
+	_, err = os.Stat(record.Backup)
+	if err != nil {
+		return fmt.Errorf("%w. Backup %s of version %s is missing. %w", ErrNoRollback, record.Backup, record.PreviousVersion, err)
+	}
+
+	// The current version is moved aside rather than removed, the running
+	// executable cannot be removed on Windows.
+	var aside string
+	if record.Dir {
+		aside = record.Target + ".old-" + uuid.NewString()
+	} else {
+		aside = record.Target + "." + uuid.NewString() + ".old"
+	}
+	err = os.Rename(record.Target, aside)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	moved := err == nil
+
+	if record.Dir {
+		err = os.Rename(record.Backup, record.Target)
+	} else {
+		err = moveFile(record.Backup, record.Target)
+	}
+	if err != nil {
+		if moved {
+			restoreErr := os.Rename(aside, record.Target)
+			if restoreErr != nil {
+				return fmt.Errorf("Failed to roll back %s and failed to restore version %s from %s. %w", record.Target, record.Version, aside, errors.Join(err, restoreErr))
+			}
+		}
+		return err
+	}
+
+	err = os.Remove(updater.config.RollbackPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if moved {
+		// Left for Cleanup if it cannot be removed yet.
+		removeBackup(aside, record.Dir)
+	}
+	updater.logger().Info("Rolled back", "target", record.Target, "version", record.PreviousVersion)
+	updater.emit(Event{Type: EventRolledBack, Version: record.PreviousVersion})
+	return nil
+}
+
+// rollbackBackup is the backup Cleanup must keep.
+func (updater *Updater) rollbackBackup() string {
+	if updater.config.RollbackPath == "" {
+		return ""
+	}
+
+	record, err := updater.readRollbackRecord()
+	if err != nil || record == nil {
+		return ""
+	}
+
+	return record.Backup
+}
@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"os"
+	"strings"
+	"time"
+)
+
+// UpdaterRollout limits a release to a percentage of machines. Before Start
+// no machine is offered the release. When End is set the percentage grows
+// linearly from Percent at Start to 100 at End.
+type UpdaterRollout struct {
+	Percent float64   `json:"percent"`
+	Start   time.Time `json:"start,omitempty"`
+	End     time.Time `json:"end,omitempty"`
+}
+
+// percentAt returns the percentage of machines the release is rolled out to
+// at now.
+func (rollout *UpdaterRollout) percentAt(now time.Time) float64 {
+	if !rollout.Start.IsZero() && now.Before(rollout.Start) {
+		return 0
+	}
+
+	if rollout.End.IsZero() || rollout.Start.IsZero() || !rollout.End.After(rollout.Start) {
+		return rollout.Percent
+	}
+	if !now.Before(rollout.End) {
+		return 100
+	}
+
+	elapsed := float64(now.Sub(rollout.Start)) / float64(rollout.End.Sub(rollout.Start))
+	return rollout.Percent + (100-rollout.Percent)*elapsed
+}
+
+// cohort places the machine in [0, 100) for the version. The cohort is stable
+// across checks but differs between versions, so that the same machines are
+// not always the first to update.
+func cohort(machineId string, version string) float64 {
+	sum := sha256.Sum256([]byte(machineId + "\n" + strings.TrimSpace(version)))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53) * 100
+}
+
+func (updater *Updater) machineId() string {
+	if updater.config.MachineId != "" {
+		return updater.config.MachineId
+	}
+
+	if id := readMachineId(); id != "" {
+		return id
+	}
+
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// inRollout reports whether the machine is offered the manifest release.
+func (updater *Updater) inRollout(manifest *UpdaterManifest) bool {
+	if manifest.Rollout == nil || updater.config.IgnoreRollout {
+		return true
+	}
+
+	return cohort(updater.machineId(), manifest.Version) < manifest.Rollout.percentAt(time.Now())
+}
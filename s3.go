@@ -0,0 +1,436 @@
+package updater
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const emptyPayloadSha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Source reads the manifest and assets from an Amazon S3 bucket, or an S3
+// compatible store, signing requests with AWS Signature Version 4.
+type S3Source struct {
+	Bucket string
+	// Prefix is prepended to the manifest and asset names, e.g., "releases/".
+	Prefix string
+	// Region defaults to AWS_REGION, AWS_DEFAULT_REGION or us-east-1.
+	Region string
+	// Manifest is the key of the manifest, relative to Prefix.
+	Manifest string
+	// Endpoint is the url of an S3 compatible store, e.g., MinIO. Objects
+	// are addressed in the path style. Defaults to Amazon S3.
+	Endpoint string
+	// AccessKeyId, SecretAccessKey and SessionToken set static credentials.
+	// Otherwise credentials are read from the environment, the shared
+	// credentials file (Profile, AWS_PROFILE or default), the ECS container
+	// endpoint or the EC2 instance metadata service.
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Profile         string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	credentialsMu sync.Mutex
+	credentials   *awsCredentials
+}
+
+type awsCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+func (credentials *awsCredentials) expired() bool {
+	return !credentials.Expiration.IsZero() && time.Now().Add(5*time.Minute).After(credentials.Expiration)
+}
+
+func (source *S3Source) client() *http.Client {
+	if source.Client != nil {
+		return source.Client
+	}
+
+	return http.DefaultClient
+}
+
+func (source *S3Source) region() string {
+	for _, region := range []string{source.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if region != "" {
+			return region
+		}
+	}
+
+	return "us-east-1"
+}
+
+func (source *S3Source) objectUrl(name string) (*url.URL, error) {
+	key := strings.TrimPrefix(source.Prefix+name, "/")
+
+	if source.Endpoint != "" {
+		endpoint, err := url.Parse(strings.TrimSuffix(source.Endpoint, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid S3 endpoint %s. %w", source.Endpoint, err)
+		}
+		endpoint.Path += "/" + source.Bucket + "/" + key
+		return endpoint, nil
+	}
+
+	// Bucket names containing dots do not match the wildcard certificate of
+	// virtual hosted style urls.
+	if strings.Contains(source.Bucket, ".") {
+		return &url.URL{Scheme: "https", Host: fmt.Sprintf("s3.%s.amazonaws.com", source.region()), Path: "/" + source.Bucket + "/" + key}, nil
+	}
+
+	return &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", source.Bucket, source.region()), Path: "/" + key}, nil
+}
+
+func (source *S3Source) FetchManifest(ctx context.Context) (io.ReadCloser, int64, error) {
+	return source.FetchAsset(ctx, source.Manifest)
+}
+
+func (source *S3Source) FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	objectUrl, err := source.objectUrl(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", objectUrl.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	credentials, err := source.resolveCredentials(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	signAwsRequest(request, credentials, source.region(), "s3", time.Now().UTC())
+
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("Error downloading s3://%s/%s. %w", source.Bucket, strings.TrimPrefix(source.Prefix+name, "/"), httpStatusError(resp))
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+// awsUriEncode percent encodes everything but the unreserved characters, and
+// slashes when encoding a path.
+func awsUriEncode(value string, path bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || (path && b == '/') {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return encoded.String()
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signAwsRequest signs a request without a body with AWS Signature Version 4.
+func signAwsRequest(request *http.Request, credentials *awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", emptyPayloadSha256)
+	if credentials.Token != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.Token)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := request.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			canonicalQuery = append(canonicalQuery, awsUriEncode(key, false)+"="+awsUriEncode(value, false))
+		}
+	}
+
+	canonicalPath := request.URL.Path
+	if canonicalPath == "" {
+		canonicalPath = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		awsUriEncode(canonicalPath, true),
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadSha256,
+	}, "\n")
+	// The request is sent with the same encoding that was signed.
+	request.URL.RawPath = awsUriEncode(canonicalPath, true)
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSha256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, service)
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", credentials.AccessKeyId, scope, signedHeaders, signature))
+}
+
+func (source *S3Source) resolveCredentials(ctx context.Context) (*awsCredentials, error) {
+	if source.AccessKeyId != "" {
+		return &awsCredentials{AccessKeyId: source.AccessKeyId, SecretAccessKey: source.SecretAccessKey, Token: source.SessionToken}, nil
+	}
+
+	source.credentialsMu.Lock()
+	defer source.credentialsMu.Unlock()
+
+	if source.credentials != nil && !source.credentials.expired() {
+		return source.credentials, nil
+	}
+
+	credentials, err := source.credentialChain(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source.credentials = credentials
+
+	return credentials, nil
+}
+
+func (source *S3Source) credentialChain(ctx context.Context) (*awsCredentials, error) {
+	if accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyId != "" {
+		return &awsCredentials{
+			AccessKeyId:     accessKeyId,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Token:           os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	credentials, err := source.sharedCredentials()
+	if credentials != nil || err != nil {
+		return credentials, err
+	}
+
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return source.containerCredentials(ctx)
+	}
+
+	credentials, err = source.instanceCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("No AWS credentials found in the environment, shared credentials file, or instance metadata. %w", err)
+	}
+
+	return credentials, nil
+}
+
+// sharedCredentials reads the profile from the shared credentials file. A
+// missing file or profile is not an error.
+func (source *S3Source) sharedCredentials() (*awsCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profile := source.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+
+	var credentials awsCredentials
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			credentials.AccessKeyId = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			credentials.Token = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read %s. %w", path, err)
+	}
+
+	if credentials.AccessKeyId == "" {
+		return nil, nil
+	}
+
+	return &credentials, nil
+}
+
+func (source *S3Source) getCredentials(request *http.Request) (*awsCredentials, error) {
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Error requesting AWS credentials from %s. %w", request.URL, httpStatusError(resp))
+	}
+
+	var credentials awsCredentials
+	err = json.NewDecoder(resp.Body).Decode(&credentials)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid AWS credentials from %s. %w", request.URL, err)
+	}
+
+	return &credentials, nil
+}
+
+func (source *S3Source) containerCredentials(ctx context.Context) (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = "http://169.254.170.2" + relative
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		request.Header.Set("Authorization", token)
+	}
+
+	return source.getCredentials(request)
+}
+
+// instanceCredentials reads the credentials of the instance role from the
+// EC2 instance metadata service using IMDSv2.
+func (source *S3Source) instanceCredentials(ctx context.Context) (*awsCredentials, error) {
+	const metadataUrl = "http://169.254.169.254/latest"
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "PUT", metadataUrl+"/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	resp, err := source.client().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	token, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Error requesting an instance metadata token. %w", httpStatusError(resp))
+	}
+
+	request, err = http.NewRequestWithContext(ctx, "GET", metadataUrl+"/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token", string(token))
+	resp, err = source.client().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Error requesting the instance role. %w", httpStatusError(resp))
+	}
+	role := strings.TrimSpace(strings.Split(string(roles), "\n")[0])
+	if role == "" {
+		return nil, fmt.Errorf("The instance has no IAM role")
+	}
+
+	request, err = http.NewRequestWithContext(ctx, "GET", metadataUrl+"/meta-data/iam/security-credentials/"+url.PathEscape(role), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token", string(token))
+
+	return source.getCredentials(request)
+}
@@ -0,0 +1,113 @@
+package updater
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type semVersion struct {
+	major      uint64
+	minor      uint64
+	patch      uint64
+	prerelease []string
+}
+
+func parseVersion(version string) (*semVersion, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexByte(trimmed, '+'); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+
+	prerelease := ""
+	if i := strings.IndexByte(trimmed, '-'); i >= 0 {
+		prerelease = trimmed[i+1:]
+		trimmed = trimmed[:i]
+	}
+
+	parts := strings.Split(trimmed, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Invalid semantic version %q", version)
+	}
+
+	numbers := make([]uint64, 3)
+	for i, part := range parts {
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid semantic version %q", version)
+		}
+		numbers[i] = number
+	}
+
+	parsed := &semVersion{
+		major: numbers[0],
+		minor: numbers[1],
+		patch: numbers[2],
+	}
+	if prerelease != "" {
+		parsed.prerelease = strings.Split(prerelease, ".")
+		for _, identifier := range parsed.prerelease {
+			if identifier == "" {
+				return nil, fmt.Errorf("Invalid semantic version %q", version)
+			}
+		}
+	}
+
+	return parsed, nil
+}
+
+func compareUint(a uint64, b uint64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func comparePrereleaseIdentifier(a string, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(aNumber, bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func (version *semVersion) compare(other *semVersion) int {
+	if result := compareUint(version.major, other.major); result != 0 {
+		return result
+	}
+	if result := compareUint(version.minor, other.minor); result != 0 {
+		return result
+	}
+	if result := compareUint(version.patch, other.patch); result != 0 {
+		return result
+	}
+
+	// A version without a prerelease has higher precedence than one with.
+	switch {
+	case len(version.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(version.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(version.prerelease) && i < len(other.prerelease); i++ {
+		if result := comparePrereleaseIdentifier(version.prerelease[i], other.prerelease[i]); result != 0 {
+			return result
+		}
+	}
+
+	return compareUint(uint64(len(version.prerelease)), uint64(len(other.prerelease)))
+}
@@ -0,0 +1,242 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrKeyNotTrusted      = errors.New("Signing key is not trusted")
+	ErrSignatureThreshold = errors.New("Not enough valid signatures")
+)
+
+func KeyFingerprint(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+func parsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid public key. %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Invalid public key. Expected %d bytes but got %d", ed25519.PublicKeySize, len(key))
+	}
+
+	return ed25519.PublicKey(key), nil
+}
+
+func (updater *Updater) readPinnedKey() (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(updater.config.PinnedKeyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePublicKey(string(data))
+}
+
+func (updater *Updater) pinKey(key ed25519.PublicKey) error {
+	err := os.MkdirAll(filepath.Dir(updater.config.PinnedKeyPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(updater.config.PinnedKeyPath, []byte(base64.StdEncoding.EncodeToString(key)), 0600)
+}
+
+// trustedKey returns the key artifacts must be signed with. A key that has not
+// been pinned yet is pinned once the TrustKey callback accepts it.
+func (updater *Updater) trustedKey(manifest *UpdaterManifest) (ed25519.PublicKey, error) {
+	pinned, err := updater.readPinnedKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(manifest.PublicKey) == "" {
+		if pinned != nil {
+			return pinned, nil
+		}
+		return nil, fmt.Errorf("%w. Manifest does not specify a public key", ErrManifestInvalid)
+	}
+
+	key, err := parsePublicKey(manifest.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if pinned != nil {
+		if !pinned.Equal(key) {
+			return nil, fmt.Errorf("%w. Manifest key %s does not match pinned key %s", ErrKeyNotTrusted, KeyFingerprint(key), KeyFingerprint(pinned))
+		}
+		return pinned, nil
+	}
+
+	fingerprint := KeyFingerprint(key)
+	if updater.config.TrustKey == nil {
+		return nil, fmt.Errorf("%w. No TrustKey callback to confirm key %s", ErrKeyNotTrusted, fingerprint)
+	}
+
+	trusted, err := updater.config.TrustKey(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if !trusted {
+		return nil, fmt.Errorf("%w. Key %s was rejected", ErrKeyNotTrusted, fingerprint)
+	}
+
+	err = updater.pinKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (updater *Updater) verifySignature(ctx context.Context, info *downloadInfo, name string, path string) error {
+	key, err := updater.trustedKey(info.manifest)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := updater.fetchAsset(ctx, info, name+".sig")
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("Invalid signature for %s. %w", name, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(key, data, signature) {
+		return fmt.Errorf("Signature verification failed for %s", name)
+	}
+
+	return nil
+}
+
+// signatureName returns the name of the detached signature of name, keeping
+// any query parameters of name.
+func signatureName(name string) (string, error) {
+	nameUrl, err := url.Parse(name)
+	if err != nil {
+		return "", err
+	}
+	nameUrl.Path += ".sig"
+
+	return nameUrl.String(), nil
+}
+
+func (updater *Updater) signingKeys() ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, encoded := range updater.config.SigningKeys {
+		key, err := parsePublicKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicate := false
+		for _, existing := range keys {
+			duplicate = duplicate || existing.Equal(key)
+		}
+		if !duplicate {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (updater *Updater) signatureThreshold(keys int) int {
+	if updater.config.SignatureThreshold > 0 {
+		return updater.config.SignatureThreshold
+	}
+
+	return keys
+}
+
+// verifyThresholdSignatures checks that data is signed by at least
+// SignatureThreshold distinct SigningKeys. The signature file holds one base64
+// encoded ed25519 signature per line.
+func (updater *Updater) verifyThresholdSignatures(name string, data []byte, encoded []byte) error {
+	keys, err := updater.signingKeys()
+	if err != nil {
+		return err
+	}
+
+	var signatures [][]byte
+	for _, line := range strings.Split(string(encoded), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return fmt.Errorf("Invalid signature for %s. %w", name, err)
+		}
+		signatures = append(signatures, signature)
+	}
+
+	valid := 0
+	for _, key := range keys {
+		for _, signature := range signatures {
+			if ed25519.Verify(key, data, signature) {
+				valid++
+				break
+			}
+		}
+	}
+
+	threshold := updater.signatureThreshold(len(keys))
+	if valid < threshold {
+		return fmt.Errorf("%w for %s. Found %d valid signatures but %d are required", ErrSignatureThreshold, name, valid, threshold)
+	}
+
+	return nil
+}
+
+func (updater *Updater) verifyManifestSignatures(ctx context.Context, data []byte) error {
+	sigName, err := signatureName(updater.config.UpdaterConfig)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := updater.fetch(ctx, sigName)
+	if err != nil {
+		return err
+	}
+
+	return updater.verifyThresholdSignatures(updater.config.UpdaterConfig, data, encoded)
+}
+
+func (updater *Updater) verifyArtifactSignatures(ctx context.Context, info *downloadInfo, name string, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := updater.fetchAsset(ctx, info, name+".sig")
+	if err != nil {
+		return err
+	}
+
+	return updater.verifyThresholdSignatures(name, data, encoded)
+}
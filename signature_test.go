@@ -0,0 +1,200 @@
+package updater
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test 1 of RFC 8032, section 7.1: the signature of the empty message.
+const (
+	rfc8032Seed      = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	rfc8032PublicKey = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	rfc8032Signature = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+)
+
+func decodeHex(t *testing.T, encoded string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// testKey returns a deterministic ed25519 key derived from seed.
+func testKey(seed byte) ed25519.PrivateKey {
+	var buf [ed25519.SeedSize]byte
+	buf[0] = seed
+	return ed25519.NewKeyFromSeed(buf[:])
+}
+
+func encodeKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func TestVerifyThresholdSignatures(t *testing.T) {
+	rfcKey := ed25519.NewKeyFromSeed(decodeHex(t, rfc8032Seed))
+	if hex.EncodeToString(rfcKey.Public().(ed25519.PublicKey)) != rfc8032PublicKey {
+		t.Fatalf("NewKeyFromSeed() public key = %x, want %s", rfcKey.Public(), rfc8032PublicKey)
+	}
+	rfcSignature := base64.StdEncoding.EncodeToString(decodeHex(t, rfc8032Signature))
+
+	data := []byte("manifest")
+	keyA, keyB, keyC, keyD := testKey(1), testKey(2), testKey(3), testKey(4)
+	sign := func(key ed25519.PrivateKey) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	}
+	signatures := func(signatures ...string) []byte {
+		return []byte(strings.Join(signatures, "\n") + "\n")
+	}
+	truncated := base64.StdEncoding.EncodeToString(ed25519.Sign(keyB, data)[:ed25519.SignatureSize-1])
+	threeKeys := []string{encodeKey(keyA), encodeKey(keyB), encodeKey(keyC)}
+
+	tests := []struct {
+		name       string
+		keys       []string
+		threshold  int
+		data       []byte
+		signatures []byte
+		wantErr    error
+	}{
+		{
+			name:       "rfc 8032",
+			keys:       []string{base64.StdEncoding.EncodeToString(decodeHex(t, rfc8032PublicKey))},
+			data:       []byte{},
+			signatures: signatures(rfcSignature),
+		},
+		{
+			name:       "2 of 3",
+			keys:       threeKeys,
+			threshold:  2,
+			data:       data,
+			signatures: signatures(sign(keyA), sign(keyC)),
+		},
+		{
+			name:       "signatures in any order with blank lines",
+			keys:       threeKeys,
+			threshold:  2,
+			data:       data,
+			signatures: signatures("", sign(keyC), "  ", sign(keyB)),
+		},
+		{
+			name:       "1 of 2 required",
+			keys:       threeKeys,
+			threshold:  2,
+			data:       data,
+			signatures: signatures(sign(keyA)),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "duplicated signature counts once",
+			keys:       threeKeys,
+			threshold:  2,
+			data:       data,
+			signatures: signatures(sign(keyA), sign(keyA)),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "duplicated key counts once",
+			keys:       []string{encodeKey(keyA), encodeKey(keyA), encodeKey(keyB)},
+			threshold:  2,
+			data:       data,
+			signatures: signatures(sign(keyA)),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "wrong key",
+			keys:       threeKeys,
+			threshold:  2,
+			data:       data,
+			signatures: signatures(sign(keyA), sign(keyD)),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "truncated signature",
+			keys:       threeKeys,
+			threshold:  2,
+			data:       data,
+			signatures: signatures(sign(keyA), truncated),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "tampered data",
+			keys:       threeKeys,
+			threshold:  1,
+			data:       []byte("manifest2"),
+			signatures: signatures(sign(keyA), sign(keyB), sign(keyC)),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "all keys required by default",
+			keys:       threeKeys,
+			data:       data,
+			signatures: signatures(sign(keyA), sign(keyB)),
+			wantErr:    ErrSignatureThreshold,
+		},
+		{
+			name:       "all keys signed",
+			keys:       threeKeys,
+			data:       data,
+			signatures: signatures(sign(keyA), sign(keyB), sign(keyC)),
+		},
+		{
+			name:       "no signatures",
+			keys:       threeKeys,
+			threshold:  1,
+			data:       data,
+			signatures: nil,
+			wantErr:    ErrSignatureThreshold,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updater := New(&UpdaterConfig{SigningKeys: test.keys, SignatureThreshold: test.threshold})
+			err := updater.verifyThresholdSignatures("manifest", test.data, test.signatures)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("verifyThresholdSignatures() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyThresholdSignatures() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyThresholdSignaturesInvalidInput(t *testing.T) {
+	data := []byte("manifest")
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(testKey(1), data))
+
+	tests := []struct {
+		name       string
+		keys       []string
+		signatures string
+	}{
+		{name: "invalid signature encoding", keys: []string{encodeKey(testKey(1))}, signatures: signature + "\n!"},
+		{name: "invalid key encoding", keys: []string{"!"}, signatures: signature},
+		{name: "truncated key", keys: []string{encodeKey(testKey(1))[:40]}, signatures: signature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updater := New(&UpdaterConfig{SigningKeys: test.keys, SignatureThreshold: 1})
+			err := updater.verifyThresholdSignatures("manifest", data, []byte(test.signatures))
+			if err == nil {
+				t.Fatal("verifyThresholdSignatures() succeeded, want an error")
+			}
+			if errors.Is(err, ErrSignatureThreshold) {
+				t.Fatalf("verifyThresholdSignatures() error = %v, want an invalid input error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Source fetches the manifest and the files hosted alongside it in place of
+// the BaseUrl, e.g., from storage that requires its own client or
+// authentication.
+type Source interface {
+	// FetchManifest returns the manifest and its size, or -1 when unknown.
+	FetchManifest(ctx context.Context) (io.ReadCloser, int64, error)
+	// FetchAsset returns the named file and its size, or -1 when unknown.
+	// Names are the rendered archive/binary names and the names of the files
+	// published next to them, such as signatures.
+	FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error)
+}
+
+// FileSource reads the manifest and assets from a local directory, e.g., a
+// mounted network share or removable media.
+type FileSource struct {
+	Dir string
+	// Manifest is the name of the manifest within Dir.
+	Manifest string
+}
+
+func (source *FileSource) open(name string) (io.ReadCloser, int64, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return nil, 0, fmt.Errorf("Invalid asset name %s. Expected a path within %s", name, source.Dir)
+	}
+
+	file, err := os.Open(filepath.Join(source.Dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, info.Size(), nil
+}
+
+func (source *FileSource) FetchManifest(ctx context.Context) (io.ReadCloser, int64, error) {
+	return source.open(source.Manifest)
+}
+
+func (source *FileSource) FetchAsset(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	return source.open(name)
+}
+
+// fetchAsset downloads a file published next to an archive/binary, such as
+// its signature.
+func (updater *Updater) fetchAsset(ctx context.Context, info *downloadInfo, name string) ([]byte, error) {
+	if updater.config.Source != nil {
+		return updater.readAsset(ctx, name)
+	}
+
+	resp, err := updater.get(ctx, updater.downloadUrl(ctx, info, func() string { return name }, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return updater.readSidecar(name, resp.ContentLength, resp.Body)
+}
+
+func (updater *Updater) readAsset(ctx context.Context, name string) ([]byte, error) {
+	reader, size, err := updater.config.Source.FetchAsset(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return updater.readSidecar(name, size, reader)
+}
+
+func (updater *Updater) downloadSource(ctx context.Context, info *downloadInfo, name string, destination string) error {
+	reader, size, err := updater.config.Source.FetchAsset(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	limited, err := updater.limitDownload(name, 0, size, reader)
+	if err != nil {
+		return err
+	}
+
+	err = updater.checkDiskSpace(info, name, size)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, updater.newProgress(size).download(updater, name).wrap(limited))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// releaseAssets holds the download urls of the assets of the release last
+// returned by a forge source, keyed by asset name.
+type releaseAssets struct {
+	mu   sync.Mutex
+	urls map[string]string
+}
+
+func (assets *releaseAssets) set(urls map[string]string) {
+	assets.mu.Lock()
+	defer assets.mu.Unlock()
+
+	assets.urls = urls
+}
+
+func (assets *releaseAssets) url(name string) (string, error) {
+	assets.mu.Lock()
+	defer assets.mu.Unlock()
+
+	assetUrl, ok := assets.urls[name]
+	if !ok {
+		return "", fmt.Errorf("Release has no asset named %s. %w", name, os.ErrNotExist)
+	}
+
+	return assetUrl, nil
+}
+
+// forgeRelease is a release of a forge source.
+type forgeRelease struct {
+	version     string
+	notes       string
+	publishedAt time.Time
+	url         string
+}
+
+// releaseManifestJson builds the manifest of a release from the urls of its
+// assets, using a checksums.txt asset as the manifest checksums.
+func releaseManifestJson(ctx context.Context, release forgeRelease, urls map[string]string, binary string, name string, fetch func(ctx context.Context, url string) ([]byte, error)) ([]byte, error) {
+	names := make([]string, 0, len(urls))
+	for assetName := range urls {
+		names = append(names, assetName)
+	}
+	sort.Strings(names)
+
+	asset, err := selectReleaseAsset(names, runtime.GOOS, runtime.GOARCH, detectLibc())
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := releaseManifest(release.version, asset, binary, name)
+	manifest.ReleaseNotes = release.notes
+	manifest.PublishedAt = release.publishedAt
+	manifest.Url = release.url
+	for _, assetName := range names {
+		if isChecksumsAsset(assetName) {
+			data, err := fetch(ctx, urls[assetName])
+			if err != nil {
+				return nil, err
+			}
+			manifest.Checksums = parseChecksums(data)
+			break
+		}
+	}
+
+	return json.Marshal(manifest)
+}
+
+func readResponse(resp *http.Response, err error) (io.ReadCloser, int64, error) {
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("Error downloading %s. %w", redactUrl(resp.Request.URL), httpStatusError(resp))
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
@@ -0,0 +1,52 @@
+package updater
+
+type UpdaterState int
+
+const (
+	StateIdle UpdaterState = iota
+	StateChecking
+	StateDownloading
+	StateVerifying
+	StateReadyToInstall
+	StateUpdated
+	StateFailed
+)
+
+func (state UpdaterState) String() string {
+	switch state {
+	case StateIdle:
+		return "idle"
+	case StateChecking:
+		return "checking"
+	case StateDownloading:
+		return "downloading"
+	case StateVerifying:
+		return "verifying"
+	case StateReadyToInstall:
+		return "ready-to-install"
+	case StateUpdated:
+		return "updated"
+	case StateFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+func (updater *Updater) State() UpdaterState {
+	updater.stateMu.Lock()
+	defer updater.stateMu.Unlock()
+
+	return updater.state
+}
+
+func (updater *Updater) setState(state UpdaterState) {
+	updater.stateMu.Lock()
+	changed := updater.state != state
+	updater.state = state
+	updater.stateMu.Unlock()
+
+	if changed && updater.config.OnStateChange != nil {
+		updater.config.OnStateChange(state)
+	}
+}
@@ -0,0 +1,144 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const storeFile = "updater-state.json"
+
+// store is the updater state persisted across runs at StorePath.
+type store struct {
+	SkippedVersions []string   `json:"skippedVersions,omitempty"`
+	LastCheck       *lastCheck `json:"lastCheck,omitempty"`
+}
+
+// storePath defaults to a directory named after the executable in the user
+// config dir.
+func (updater *Updater) storePath() (string, error) {
+	if updater.config.StorePath != "" {
+		return updater.config.StorePath, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(executable), filepath.Ext(executable))
+
+	return filepath.Join(configDir, name, storeFile), nil
+}
+
+func (updater *Updater) readStore() (*store, error) {
+	path, err := updater.storePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &store{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored store
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid updater state %s. %w", path, err)
+	}
+
+	return &stored, nil
+}
+
+// updateStore applies update to the stored state and writes it back.
+func (updater *Updater) updateStore(update func(stored *store)) error {
+	updater.storeMu.Lock()
+	defer updater.storeMu.Unlock()
+
+	stored, err := updater.readStore()
+	if err != nil {
+		return err
+	}
+	update(stored)
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	path, err := updater.storePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tempPath := path + "." + uuid.NewString()
+	err = os.WriteFile(tempPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tempPath, path)
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
+
+// SkipVersion persists that the user chose to skip version, so that
+// CheckForAvailableUpdate no longer reports it. Newer versions are still
+// reported.
+func (updater *Updater) SkipVersion(version string) error {
+	version = strings.TrimSpace(version)
+	return updater.updateStore(func(stored *store) {
+		if !stored.skipped(version) {
+			stored.SkippedVersions = append(stored.SkippedVersions, version)
+		}
+	})
+}
+
+// ClearSkippedVersions forgets the versions skipped with SkipVersion.
+func (updater *Updater) ClearSkippedVersions() error {
+	return updater.updateStore(func(stored *store) {
+		stored.SkippedVersions = nil
+	})
+}
+
+func (stored *store) skipped(version string) bool {
+	for _, skipped := range stored.SkippedVersions {
+		if skipped == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionSkipped reports whether the user skipped version. The state failing
+// to load does not prevent updates.
+func (updater *Updater) versionSkipped(version string) bool {
+	updater.storeMu.Lock()
+	defer updater.storeMu.Unlock()
+
+	stored, err := updater.readStore()
+	if err != nil {
+		return false
+	}
+
+	return stored.skipped(version)
+}
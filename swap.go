@@ -0,0 +1,162 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// The swap journal is written next to the target binary while it is being
+// replaced so that a swap interrupted by a crash or a power loss can be
+// completed, or undone, by recoverSwap.
+const (
+	// swapStaged means the new binary is complete at Staged, next to the
+	// target, and the target is untouched.
+	swapStaged = "staged"
+	// swapBackedUp means the previous binary is at Backup. On Windows the
+	// target no longer exists until Staged is renamed into place.
+	swapBackedUp = "backedUp"
+)
+
+type swapJournal struct {
+	Target string `json:"target"`
+	Staged string `json:"staged"`
+	Backup string `json:"backup,omitempty"`
+	Phase  string `json:"phase"`
+}
+
+func journalPath(binaryPath string) string {
+	return filepath.Join(filepath.Dir(binaryPath), "."+filepath.Base(binaryPath)+".updater-journal")
+}
+
+func writeJournal(journal *swapJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+
+	path := journalPath(journal.Target)
+	tempPath := path + "." + uuid.NewString()
+	err = os.WriteFile(tempPath, data, 0600)
+	if err == nil {
+		err = syncFile(tempPath)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+func removeJournal(binaryPath string) {
+	os.Remove(journalPath(binaryPath))
+	_ = syncDir(filepath.Dir(binaryPath))
+}
+
+// recoverSwap completes a swap of the binary at binaryPath that was
+// interrupted. A missing binary is restored from the backup when there is
+// one, or else replaced by the staged binary. The staged binary and the
+// backup left behind are then removed, unless the backup is a retained
+// version.
+func (updater *Updater) recoverSwap(binaryPath string) error {
+	data, err := os.ReadFile(journalPath(binaryPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var journal swapJournal
+	err = json.Unmarshal(data, &journal)
+	if err != nil || journal.Target != binaryPath {
+		removeJournal(binaryPath)
+		return nil
+	}
+
+	_, err = os.Stat(binaryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		_, backupErr := os.Stat(journal.Backup)
+		if journal.Phase == swapBackedUp && journal.Backup != "" && backupErr == nil {
+			updater.logger().Warn("Restoring the binary of an interrupted update", "target", binaryPath, "backup", journal.Backup)
+			err = moveFile(journal.Backup, binaryPath)
+		} else {
+			updater.logger().Warn("Completing an interrupted update", "target", binaryPath)
+			err = renameFile(journal.Staged, binaryPath)
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to recover the interrupted update of %s. %w", binaryPath, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	os.Remove(journal.Staged)
+	if journal.Backup != "" && !updater.isRetained(journal.Backup) {
+		os.Remove(journal.Backup)
+	}
+	removeJournal(binaryPath)
+	return nil
+}
+
+// swapBinary replaces the binary at binaryPath with the new binary at
+// tempPath, on the same volume, journaling each phase. The previous binary is
+// kept at backupPath, unless empty. On Unix the binary is replaced by a
+// single atomic rename and is never missing.
+func (updater *Updater) swapBinary(tempPath string, binaryPath string, backupPath string) error {
+	journal := &swapJournal{Target: binaryPath, Staged: tempPath, Backup: backupPath, Phase: swapStaged}
+	err := writeJournal(journal)
+	if err != nil {
+		return err
+	}
+
+	if backupPath != "" {
+		err = backupBinary(binaryPath, backupPath)
+		if err == nil {
+			updater.logger().Debug("Backed up binary", "target", binaryPath, "backup", backupPath)
+			journal.Phase = swapBackedUp
+			err = writeJournal(journal)
+		}
+		if err != nil {
+			undoBackup(binaryPath, backupPath)
+			removeJournal(binaryPath)
+			return err
+		}
+	}
+
+	err = renameFile(tempPath, binaryPath)
+	if err == nil {
+		err = syncDir(filepath.Dir(binaryPath))
+	}
+	if err != nil {
+		if backupPath != "" {
+			undoBackup(binaryPath, backupPath)
+		}
+		removeJournal(binaryPath)
+		return err
+	}
+
+	removeJournal(binaryPath)
+	updater.logger().Debug("Swapped binary", "target", binaryPath)
+	return nil
+}
+
+// undoBackup moves the backup back in place when the binary was moved aside,
+// and otherwise removes the backup.
+func undoBackup(binaryPath string, backupPath string) {
+	_, err := os.Stat(binaryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		_ = moveFile(backupPath, binaryPath)
+		return
+	}
+
+	os.Remove(backupPath)
+}
@@ -0,0 +1,78 @@
+package updater
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoverSwap(t *testing.T) {
+	tests := []struct {
+		name       string
+		phase      string
+		target     bool
+		staged     bool
+		backup     bool
+		wantTarget string
+	}{
+		{name: "staged with target", phase: swapStaged, target: true, staged: true, backup: true, wantTarget: "old"},
+		{name: "backed up with target", phase: swapBackedUp, target: true, staged: true, backup: true, wantTarget: "old"},
+		{name: "swapped", phase: swapBackedUp, target: true, backup: true, wantTarget: "new"},
+		{name: "backed up without target", phase: swapBackedUp, staged: true, backup: true, wantTarget: "old"},
+		{name: "staged without target", phase: swapStaged, staged: true, wantTarget: "new"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			journal := &swapJournal{
+				Target: filepath.Join(dir, "app"),
+				Staged: filepath.Join(dir, "app.staged"),
+				Backup: filepath.Join(dir, "app.backup"),
+				Phase:  test.phase,
+			}
+			files := []struct {
+				create  bool
+				path    string
+				content string
+			}{
+				{test.target, journal.Target, test.wantTarget},
+				{test.staged, journal.Staged, "new"},
+				{test.backup, journal.Backup, "old"},
+			}
+			for _, file := range files {
+				if !file.create {
+					continue
+				}
+				err := os.WriteFile(file.path, []byte(file.content), 0700)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := writeJournal(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = New(&UpdaterConfig{}).recoverSwap(journal.Target)
+			if err != nil {
+				t.Fatalf("recoverSwap() error = %v", err)
+			}
+
+			got, err := os.ReadFile(journal.Target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.wantTarget {
+				t.Errorf("recoverSwap() target = %q, want %q", got, test.wantTarget)
+			}
+			for _, path := range []string{journal.Staged, journal.Backup, journalPath(journal.Target)} {
+				_, err := os.Stat(path)
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("recoverSwap() left %s behind", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
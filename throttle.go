@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"strings"
+	"time"
+)
+
+// lastCheck is the result of the last check for updates, reused within
+// MinCheckInterval. Version and Manifest are empty when no update was
+// available.
+type lastCheck struct {
+	Time           time.Time `json:"time"`
+	CurrentVersion string    `json:"currentVersion"`
+	Channel        string    `json:"channel,omitempty"`
+	Version        string    `json:"version,omitempty"`
+	ReleaseNotes   string    `json:"releaseNotes,omitempty"`
+	PublishedAt    time.Time `json:"publishedAt,omitempty"`
+	Url            string    `json:"url,omitempty"`
+	// Manifest is the manifest of the available update.
+	Manifest *UpdaterManifest `json:"manifest,omitempty"`
+}
+
+// cachedCheck returns the result of the last check when it was made within
+// MinCheckInterval for the same current version and channel.
+func (updater *Updater) cachedCheck() (*UpdateInfo, bool) {
+	if updater.config.MinCheckInterval <= 0 {
+		return nil, false
+	}
+
+	updater.storeMu.Lock()
+	stored, err := updater.readStore()
+	updater.storeMu.Unlock()
+	if err != nil || stored.LastCheck == nil {
+		return nil, false
+	}
+
+	last := stored.LastCheck
+	elapsed := time.Since(last.Time)
+	if elapsed < 0 || elapsed >= updater.config.MinCheckInterval ||
+		last.CurrentVersion != strings.TrimSpace(updater.config.CurrentVersion) || last.Channel != updater.Channel() {
+		return nil, false
+	}
+
+	if last.Version == "" || stored.skipped(last.Version) {
+		return nil, true
+	}
+	if last.Manifest == nil {
+		// Checks persisted without the manifest are made again rather than
+		// returning an UpdateInfo without one.
+		return nil, false
+	}
+
+	return &UpdateInfo{
+		Version:      last.Version,
+		ReleaseNotes: last.ReleaseNotes,
+		PublishedAt:  last.PublishedAt,
+		Url:          last.Url,
+		Manifest:     last.Manifest,
+	}, true
+}
+
+// saveCheck persists the result of a check. Failing to persist it only means
+// the next check is not throttled.
+func (updater *Updater) saveCheck(info *UpdateInfo) {
+	if updater.config.MinCheckInterval <= 0 {
+		return
+	}
+
+	last := &lastCheck{
+		Time:           time.Now(),
+		CurrentVersion: strings.TrimSpace(updater.config.CurrentVersion),
+		Channel:        updater.Channel(),
+	}
+	if info != nil {
+		last.Version = info.Version
+		last.ReleaseNotes = info.ReleaseNotes
+		last.PublishedAt = info.PublishedAt
+		last.Url = info.Url
+		last.Manifest = info.Manifest
+	}
+
+	updater.updateStore(func(stored *store) {
+		stored.LastCheck = last
+	})
+}
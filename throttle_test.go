@@ -0,0 +1,62 @@
+package updater
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachedCheck(t *testing.T) {
+	manifest := &UpdaterManifest{Version: "1.1.0", Binary: "app"}
+
+	tests := []struct {
+		name   string
+		saved  *UpdateInfo
+		want   *UpdateInfo
+		wantOk bool
+	}{
+		{
+			name:   "update",
+			saved:  &UpdateInfo{Version: "1.1.0", Manifest: manifest},
+			want:   &UpdateInfo{Version: "1.1.0", Manifest: manifest},
+			wantOk: true,
+		},
+		{
+			name:   "no update",
+			saved:  nil,
+			wantOk: true,
+		},
+		{
+			name:  "update without a manifest",
+			saved: &UpdateInfo{Version: "1.1.0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updater := New(&UpdaterConfig{
+				CurrentVersion:   "1.0.0",
+				StorePath:        filepath.Join(t.TempDir(), "updater-state.json"),
+				MinCheckInterval: time.Hour,
+			})
+			updater.saveCheck(test.saved)
+
+			got, ok := updater.cachedCheck()
+			if ok != test.wantOk {
+				t.Fatalf("cachedCheck() ok = %v, want %v", ok, test.wantOk)
+			}
+			if test.want == nil {
+				if got != nil {
+					t.Fatalf("cachedCheck() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Version != test.want.Version {
+				t.Fatalf("cachedCheck() = %+v, want %+v", got, test.want)
+			}
+			if got.Manifest == nil || got.Manifest.Version != manifest.Version || got.Manifest.Binary != manifest.Binary {
+				t.Errorf("cachedCheck() manifest = %+v, want %+v", got.Manifest, manifest)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package updater
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Tracer starts spans for the steps of checks and updates, e.g., backed by
+// OpenTelemetry. Spans are started with the context of the caller, or of the
+// enclosing span, and the returned context is used for the requests made
+// within the span.
+//
+// Spans: updater.Check, updater.Update, updater.FetchManifest,
+// updater.Download, updater.Verify, updater.Extract and updater.Apply.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...slog.Attr)
+	// End ends the span, recording err when the step failed.
+	End(err error)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+func (noopSpan) End(error)                  {}
+
+func (updater *Updater) startSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	if updater.config.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return updater.config.Tracer.Start(ctx, name, attrs...)
+}
@@ -0,0 +1,165 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrNotInTransparencyLog = errors.New("Artifact is not included in the transparency log")
+
+// inclusionProof is the <name>.tlog document proving that an entry for the
+// artifact is included in the transparency log.
+type inclusionProof struct {
+	Body       string   `json:"body"`
+	LogIndex   uint64   `json:"logIndex"`
+	Hashes     []string `json:"hashes"`
+	Checkpoint string   `json:"checkpoint"`
+	Signature  string   `json:"signature"`
+}
+
+type tlogEntry struct {
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+}
+
+// parseCheckpoint parses a signed tree head in the checkpoint format, an
+// origin line followed by the tree size and the base64 encoded root hash.
+func parseCheckpoint(checkpoint string) (uint64, []byte, error) {
+	lines := strings.Split(checkpoint, "\n")
+	if len(lines) < 3 {
+		return 0, nil, fmt.Errorf("Invalid checkpoint. Expected origin, tree size and root hash")
+	}
+
+	treeSize, err := strconv.ParseUint(lines[1], 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Invalid checkpoint tree size. %w", err)
+	}
+
+	rootHash, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return 0, nil, fmt.Errorf("Invalid checkpoint root hash. %w", err)
+	}
+
+	return treeSize, rootHash, nil
+}
+
+func hashChildren(left []byte, right []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte{1})
+	hash.Write(left)
+	hash.Write(right)
+	return hash.Sum(nil)
+}
+
+// verifyInclusion verifies an RFC 9162 inclusion proof of leafHash at index
+// in a tree of treeSize leaves with the given root hash.
+func verifyInclusion(leafHash []byte, index uint64, treeSize uint64, proof [][]byte, rootHash []byte) bool {
+	if index >= treeSize {
+		return false
+	}
+
+	fn := index
+	sn := treeSize - 1
+	hash := leafHash
+	for _, sibling := range proof {
+		if sn == 0 {
+			return false
+		}
+
+		if fn&1 == 1 || fn == sn {
+			hash = hashChildren(sibling, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = hashChildren(hash, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && bytes.Equal(hash, rootHash)
+}
+
+func (proof *inclusionProof) verify(key ed25519.PublicKey) ([]byte, error) {
+	signature, err := base64.StdEncoding.DecodeString(proof.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid checkpoint signature. %w", err)
+	}
+	if !ed25519.Verify(key, []byte(proof.Checkpoint), signature) {
+		return nil, fmt.Errorf("Checkpoint is not signed by the transparency log key")
+	}
+
+	treeSize, rootHash, err := parseCheckpoint(proof.Checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := base64.StdEncoding.DecodeString(proof.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid entry body. %w", err)
+	}
+
+	hashes := make([][]byte, len(proof.Hashes))
+	for i, encoded := range proof.Hashes {
+		hashes[i], err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proof hash. %w", err)
+		}
+	}
+
+	leafHash := sha256.Sum256(append([]byte{0}, body...))
+	if !verifyInclusion(leafHash[:], proof.LogIndex, treeSize, hashes, rootHash) {
+		return nil, fmt.Errorf("Inclusion proof does not match the checkpoint")
+	}
+
+	return body, nil
+}
+
+func (updater *Updater) verifyTransparencyLog(ctx context.Context, info *downloadInfo, name string, path string) error {
+	key, err := parsePublicKey(updater.config.TransparencyLogKey)
+	if err != nil {
+		return err
+	}
+
+	data, err := updater.fetchAsset(ctx, info, name+".tlog")
+	if err != nil {
+		return fmt.Errorf("%w. %w", ErrNotInTransparencyLog, err)
+	}
+
+	var proof inclusionProof
+	err = json.Unmarshal(data, &proof)
+	if err != nil {
+		return fmt.Errorf("%w. Invalid proof for %s. %w", ErrNotInTransparencyLog, name, err)
+	}
+
+	body, err := proof.verify(key)
+	if err != nil {
+		return fmt.Errorf("%w. %s: %w", ErrNotInTransparencyLog, name, err)
+	}
+
+	var entry tlogEntry
+	err = json.Unmarshal(body, &entry)
+	if err != nil {
+		return fmt.Errorf("%w. Invalid entry for %s. %w", ErrNotInTransparencyLog, name, err)
+	}
+
+	actual, err := fileSha256(path)
+	if err != nil {
+		return err
+	}
+	if entry.Name != name || strings.ToLower(entry.Sha256) != actual {
+		return fmt.Errorf("%w. Logged entry is for %s with checksum %s but downloaded %s with checksum %s", ErrNotInTransparencyLog, entry.Name, entry.Sha256, name, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package updater
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The leaves of the 8 leaf tree used by the certificate transparency test
+// vectors and its root hash.
+var ctLeaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+const ctRoot = "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"
+
+func leafHash(leaf []byte) []byte {
+	hash := sha256.Sum256(append([]byte{0}, leaf...))
+	return hash[:]
+}
+
+// treeHash and auditPath are the MTH and PATH definitions of RFC 9162,
+// section 2.1.
+func treeHash(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leafHash(leaves[0])
+	}
+	k := splitPoint(len(leaves))
+	return hashChildren(treeHash(leaves[:k]), treeHash(leaves[k:]))
+}
+
+func auditPath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(auditPath(index, leaves[:k]), treeHash(leaves[k:]))
+	}
+	return append(auditPath(index-k, leaves[k:]), treeHash(leaves[:k]))
+}
+
+// splitPoint returns the largest power of two smaller than n.
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+func decodeLeaves(t *testing.T, encoded []string) [][]byte {
+	t.Helper()
+	leaves := make([][]byte, len(encoded))
+	for i, leaf := range encoded {
+		var err error
+		leaves[i], err = hex.DecodeString(leaf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return leaves
+}
+
+func TestTreeHash(t *testing.T) {
+	got := hex.EncodeToString(treeHash(decodeLeaves(t, ctLeaves)))
+	if got != ctRoot {
+		t.Fatalf("treeHash() = %s, want %s", got, ctRoot)
+	}
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	leaves := decodeLeaves(t, ctLeaves)
+
+	// Every leaf of every tree size up to 8 verifies against its own path.
+	for size := 1; size <= len(leaves); size++ {
+		root := treeHash(leaves[:size])
+		for index := 0; index < size; index++ {
+			proof := auditPath(index, leaves[:size])
+			if !verifyInclusion(leafHash(leaves[index]), uint64(index), uint64(size), proof, root) {
+				t.Errorf("verifyInclusion() of leaf %d in a tree of %d = false, want true", index, size)
+			}
+		}
+	}
+
+	root := treeHash(leaves)
+	proof := auditPath(5, leaves)
+	flipped := make([][]byte, len(proof))
+	for i, hash := range proof {
+		flipped[i] = append([]byte{}, hash...)
+	}
+	flipped[1][0] ^= 1
+	wrongRoot := append([]byte{}, root...)
+	wrongRoot[0] ^= 1
+
+	tests := []struct {
+		name     string
+		leaf     []byte
+		index    uint64
+		treeSize uint64
+		proof    [][]byte
+		root     []byte
+		want     bool
+	}{
+		{name: "valid", leaf: leaves[5], index: 5, treeSize: 8, proof: proof, root: root, want: true},
+		{name: "wrong leaf index", leaf: leaves[5], index: 4, treeSize: 8, proof: proof, root: root},
+		{name: "index past the tree", leaf: leaves[5], index: 8, treeSize: 8, proof: proof, root: root},
+		{name: "wrong tree size", leaf: leaves[6], index: 6, treeSize: 7, proof: auditPath(6, leaves), root: root},
+		{name: "wrong leaf", leaf: leaves[6], index: 5, treeSize: 8, proof: proof, root: root},
+		{name: "short proof", leaf: leaves[5], index: 5, treeSize: 8, proof: proof[:len(proof)-1], root: root},
+		{name: "extra proof hash", leaf: leaves[5], index: 5, treeSize: 8, proof: append(proof[:len(proof):len(proof)], root), root: root},
+		{name: "empty proof", leaf: leaves[5], index: 5, treeSize: 8, proof: nil, root: root},
+		{name: "tampered proof hash", leaf: leaves[5], index: 5, treeSize: 8, proof: flipped, root: root},
+		{name: "wrong root", leaf: leaves[5], index: 5, treeSize: 8, proof: proof, root: wrongRoot},
+		{name: "empty tree", leaf: leaves[0], index: 0, treeSize: 0, proof: nil, root: leafHash(leaves[0])},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := verifyInclusion(leafHash(test.leaf), test.index, test.treeSize, test.proof, test.root)
+			if got != test.want {
+				t.Fatalf("verifyInclusion() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInclusionProofVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"name":"app.tar.gz","sha256":"00"}`)
+	leaves := [][]byte{[]byte("a"), []byte("b"), body, []byte("c"), []byte("d")}
+	checkpoint := fmt.Sprintf("log.example.com\n%d\n%s\n", len(leaves), base64.StdEncoding.EncodeToString(treeHash(leaves)))
+	signature := ed25519.Sign(private, []byte(checkpoint))
+
+	var hashes []string
+	for _, hash := range auditPath(2, leaves) {
+		hashes = append(hashes, base64.StdEncoding.EncodeToString(hash))
+	}
+
+	valid := inclusionProof{
+		Body:       base64.StdEncoding.EncodeToString(body),
+		LogIndex:   2,
+		Hashes:     hashes,
+		Checkpoint: checkpoint,
+		Signature:  base64.StdEncoding.EncodeToString(signature),
+	}
+
+	tests := []struct {
+		name    string
+		key     ed25519.PublicKey
+		modify  func(proof *inclusionProof)
+		wantErr bool
+	}{
+		{name: "valid", key: public, modify: func(proof *inclusionProof) {}},
+		{name: "wrong key", key: otherKey, modify: func(proof *inclusionProof) {}, wantErr: true},
+		{
+			name: "truncated signature",
+			key:  public,
+			modify: func(proof *inclusionProof) {
+				proof.Signature = base64.StdEncoding.EncodeToString(signature[:ed25519.SignatureSize-1])
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid signature encoding",
+			key:     public,
+			modify:  func(proof *inclusionProof) { proof.Signature = "!" },
+			wantErr: true,
+		},
+		{
+			name:    "tampered checkpoint",
+			key:     public,
+			modify:  func(proof *inclusionProof) { proof.Checkpoint = strings.Replace(proof.Checkpoint, "\n5\n", "\n6\n", 1) },
+			wantErr: true,
+		},
+		{
+			name:    "wrong leaf index",
+			key:     public,
+			modify:  func(proof *inclusionProof) { proof.LogIndex = 3 },
+			wantErr: true,
+		},
+		{
+			name:    "short proof",
+			key:     public,
+			modify:  func(proof *inclusionProof) { proof.Hashes = proof.Hashes[:len(proof.Hashes)-1] },
+			wantErr: true,
+		},
+		{
+			name:    "tampered body",
+			key:     public,
+			modify:  func(proof *inclusionProof) { proof.Body = base64.StdEncoding.EncodeToString([]byte("{}")) },
+			wantErr: true,
+		},
+		{
+			name:    "invalid proof hash encoding",
+			key:     public,
+			modify:  func(proof *inclusionProof) { proof.Hashes = []string{"!"} },
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proof := valid
+			proof.Hashes = append([]string{}, valid.Hashes...)
+			test.modify(&proof)
+			got, err := proof.verify(test.key)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("verify() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify() error = %v", err)
+			}
+			if string(got) != string(body) {
+				t.Errorf("verify() = %q, want %q", got, body)
+			}
+		})
+	}
+}
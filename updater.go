@@ -1,24 +1,40 @@
 package updater
 
 import (
-	"archive/tar"
-	"archive/zip"
 	"bytes"
-	"compress/gzip"
-	"encoding/json"
+	"context"
+	"crypto"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrNotEntitled      = errors.New("Not entitled to update")
+	ErrProductMismatch  = errors.New("Manifest is for a different product")
+	ErrBuildTooOld      = errors.New("Build is older than the minimum build time")
+	ErrSmokeTestFailed  = errors.New("Smoke test failed")
+	ErrUpdateInProgress = errors.New("An update is already in progress")
+	// ErrNoUpdateAvailable is returned by Update when the latest version is
+	// not newer than the current version.
+	ErrNoUpdateAvailable = errors.New("No update available")
+	ErrManifestInvalid   = errors.New("Invalid manifest")
+)
+
 type NotSupportedError struct {
 	Platform string
 }
@@ -27,29 +43,173 @@ func (ns *NotSupportedError) Error() string {
 	return fmt.Sprintf("Self updating is not support for %s.", ns.Platform)
 }
 
+// HTTPStatusError is returned, wrapped, when a server responds with an
+// unexpected status code, e.g., to tell a missing release (404) from an
+// outage with errors.As.
+type HTTPStatusError struct {
+	Url  string
+	Code int
+}
+
+func (hs *HTTPStatusError) Error() string {
+	return fmt.Sprintf("Status code: %d", hs.Code)
+}
+
+// httpStatusError describes the status of resp, leaving the query, e.g., a
+// presigned token, out of the Url.
+func httpStatusError(resp *http.Response) *HTTPStatusError {
+	statusErr := &HTTPStatusError{Code: resp.StatusCode}
+	if resp.Request != nil && resp.Request.URL != nil {
+		statusErr.Url = redactUrl(resp.Request.URL)
+	}
+
+	return statusErr
+}
+
 type UpdaterManifest struct {
-	Version string                       `json:"Version"`
-	Archive string                       `json:"archive"`
-	Binary  string                       `json:"binary"`
-	Os      map[string]string            `json:"os"`
-	Arch    map[string]map[string]string `json:"arch"`
+	// SchemaVersion is the schema the manifest is written in, see
+	// ManifestSchemaVersion. Manifests without one are schema 1.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
+	Version    string                       `json:"version,omitempty"`
+	Product    string                       `json:"product,omitempty"`
+	Archive    string                       `json:"archive,omitempty"`
+	Binary     string                       `json:"binary,omitempty"`
+	Os         map[string]string            `json:"os,omitempty"`
+	Arch       map[string]map[string]string `json:"arch,omitempty"`
+	PublicKey  string                       `json:"publicKey,omitempty"`
+	Urls       map[string][]string          `json:"urls,omitempty"`
+	Releases   []UpdaterRelease             `json:"releases,omitempty"`
+	Jws        map[string]string            `json:"jws,omitempty"`
+	BuildTime  time.Time                    `json:"buildTime"`
+	KillSwitch []KillSwitch                 `json:"killSwitch,omitempty"`
+	Migration  string                       `json:"migration,omitempty"`
+	Checksums  map[string]string            `json:"checksums,omitempty"`
+	ArchiveExt map[string]string            `json:"archiveExt,omitempty"`
+	Patches    map[string]string            `json:"patches,omitempty"`
+	Sizes      map[string]int64             `json:"sizes,omitempty"`
+	Channels   map[string]*UpdaterManifest  `json:"channels,omitempty"`
+	Rollout    *UpdaterRollout              `json:"rollout,omitempty"`
+	// ReleaseNotes, PublishedAt and Url describe the release to users, Url
+	// being a link to the release page.
+	ReleaseNotes string    `json:"releaseNotes,omitempty"`
+	PublishedAt  time.Time `json:"publishedAt,omitempty"`
+	Url          string    `json:"url,omitempty"`
+	// Versions are releases other than Version that can be installed with
+	// UpdateTo, keyed by version.
+	Versions map[string]*UpdaterManifest `json:"versions,omitempty"`
+
+	// assetUrls are the download urls of artifacts resolved by a source
+	// other than the BaseUrl, keyed by artifact name.
+	assetUrls map[string]string
 }
 
+type MissingTargetPolicy string
+
+const (
+	MissingTargetFail   MissingTargetPolicy = "fail"
+	MissingTargetCreate MissingTargetPolicy = "create"
+)
+
 type UpdaterConfig struct {
-	CurrentVersion string
-	BaseUrl        string
-	UpdaterConfig  string
+	CurrentVersion           string
+	BaseUrl                  string
+	UpdaterConfig            string
+	MaxRetries               int
+	RetryPredicate           RetryPredicate
+	RetryStatusCodes         []int
+	RetryBackoff             time.Duration
+	RetryMaxBackoff          time.Duration
+	RefreshManifestOnRetry   bool
+	OnStateChange            func(state UpdaterState)
+	OnEvent                  func(event Event)
+	LinkPolicy               *LinkPolicy
+	PinnedKeyPath            string
+	TrustKey                 func(keyFingerprint string) (bool, error)
+	ArchiveChecksumFile      string
+	MaxDownloadBytes         int64
+	MaxExtractedBytes        int64
+	MaxEntryBytes            int64
+	Transport                http.RoundTripper
+	HTTPClient               *http.Client
+	BearerToken              string
+	BasicAuth                *BasicAuth
+	RegistryAuth             map[string]*BasicAuth
+	RequestHook              func(request *http.Request)
+	TargetPath               string
+	VerifyContentDisposition bool
+	InstallDir               string
+	MissingTarget            MissingTargetPolicy
+	KeepVersions             int
+	VersionsDir              string
+	BinaryMode               os.FileMode
+	Elevation                ElevationStrategy
+	UserInstallDir           string
+	Entitlement              func(ctx context.Context, version string) (bool, error)
+	MaintenanceWindow        *MaintenanceWindow
+	JwsKey                   crypto.PublicKey
+	WindowsCleanupStrategy   WindowsCleanupStrategy
+	ExpectedProduct          string
+	ReportEndpoint           string
+	MinBuildTime             time.Time
+	ReadyToSwap              func() (bool, error)
+	ReadyToSwapTimeout       time.Duration
+	ReadyToSwapInterval      time.Duration
+	IpfsGateway              string
+	MinBatteryPercent        int
+	SmokeTest                func(newBinaryPath string) error
+	HealthCheck              bool
+	HealthCheckArgs          []string
+	HealthCheckTimeout       time.Duration
+	AllowedChecksums         []string
+	FallbackToBinary         bool
+	SigningKeys              []string
+	SignatureThreshold       int
+	TransparencyLogKey       string
+	CacheDir                 string
+	MetadataKey              string
+	MetadataFile             string
+	DestName                 string
+	MinisignPublicKey        string
+	GitHubSource             *GitHubSource
+	AllowPrerelease          bool
+	Progress                 ProgressFunc
+	RollbackPath             string
+	Source                   Source
+	Channel                  string
+	ConfirmDowngrade         func(currentVersion string, version string) bool
+	StorePath                string
+	BeforeApply              func(info UpdateInfo) error
+	AfterApply               func(info UpdateInfo) error
+	CacheManifest            bool
+	MinCheckInterval         time.Duration
+	VersionConstraint        string
+	MachineId                string
+	IgnoreRollout            bool
+	Logger                   *slog.Logger
+	Metrics                  Metrics
+	Tracer                   Tracer
 }
 
 type Updater struct {
-	config      *UpdaterConfig
-	archiveName string
-	binaryName  string
+	config   *UpdaterConfig
+	updateMu sync.Mutex
+	stateMu  sync.Mutex
+	storeMu  sync.Mutex
+	state    UpdaterState
+	channel  string
+	pending  *pendingUpdate
+	verified *verifiedManifest
+	// installedPath is the binary installed by the last update.
+	installedPath string
+	// reports tracks the reports being sent to the ReportEndpoint.
+	reports sync.WaitGroup
 }
 
 func New(config *UpdaterConfig) *Updater {
 	return &Updater{
-		config: config,
+		config:  config,
+		channel: config.Channel,
 	}
 }
 
@@ -70,81 +230,323 @@ func validateUrl(path string) error {
 	return nil
 }
 
+// joinUrl joins a path relative to base. Unlike url.JoinPath, query parameters
+// of both the base and the relative path are preserved, e.g., to select a
+// manifest variant or to carry a signed url token.
+func joinUrl(base string, relative string) (string, error) {
+	baseUrl, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	relativeUrl, err := url.Parse(relative)
+	if err != nil {
+		return "", err
+	}
+
+	joined := baseUrl.JoinPath(relativeUrl.Path)
+	switch {
+	case baseUrl.RawQuery == "":
+		joined.RawQuery = relativeUrl.RawQuery
+	case relativeUrl.RawQuery != "":
+		joined.RawQuery = baseUrl.RawQuery + "&" + relativeUrl.RawQuery
+	}
+	joined.Fragment = ""
+
+	return joined.String(), nil
+}
+
 func (updater *Updater) GetManifest() (*UpdaterManifest, error) {
-	requestUrl, err := url.JoinPath(updater.config.BaseUrl, updater.config.UpdaterConfig)
+	return updater.GetManifestContext(context.Background())
+}
+
+func (updater *Updater) GetManifestContext(ctx context.Context) (*UpdaterManifest, error) {
+	ctx, span := updater.startSpan(ctx, "updater.FetchManifest")
+	manifest, err := updater.loadManifest(ctx)
+	if err == nil {
+		span.SetAttributes(slog.String("updater.manifest.version", strings.TrimSpace(manifest.Version)))
+	}
+	span.End(err)
+
+	return manifest, err
+}
+
+func (updater *Updater) loadManifest(ctx context.Context) (*UpdaterManifest, error) {
+	if updater.config.GitHubSource != nil {
+		return updater.config.GitHubSource.manifest(ctx, updater)
+	}
+
+	manifest, err := updater.getManifest(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = validateUrl(requestUrl)
+	return manifest.channelManifest(updater.Channel())
+}
+
+// getManifest fetches and verifies the manifest, returning the manifest as
+// published, for all channels.
+func (updater *Updater) getManifest(ctx context.Context) (*UpdaterManifest, error) {
+	responseBody, contentType, unchanged, err := updater.fetchManifest(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest("GET", requestUrl, nil)
+	if unchanged {
+		if manifest := updater.lastManifest(responseBody); manifest != nil {
+			return manifest, nil
+		}
+	}
+
+	if len(updater.config.SigningKeys) > 0 {
+		err = updater.verifyManifestSignatures(ctx, responseBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if updater.config.MinisignPublicKey != "" {
+		err = updater.verifyManifestMinisign(ctx, responseBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := parseManifest(responseBody, manifestFormat(updater.config.UpdaterConfig, contentType))
 	if err != nil {
 		return nil, err
 	}
+	if manifest.SchemaVersion > ManifestSchemaVersion {
+		updater.logger().Warn("Manifest schema is newer than supported, ignoring unknown keys", "schemaVersion", manifest.SchemaVersion, "supported", ManifestSchemaVersion)
+	}
+
+	if updater.config.MetadataKey != "" {
+		err = updater.verifyManifestMetadata(ctx, responseBody, manifest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	expectedProduct := strings.TrimSpace(updater.config.ExpectedProduct)
+	if expectedProduct != "" && strings.TrimSpace(manifest.Product) != expectedProduct {
+		return nil, fmt.Errorf("%w. Expected %q but got %q", ErrProductMismatch, expectedProduct, manifest.Product)
+	}
+
+	updater.logger().Debug("Fetched manifest", "version", manifest.Version)
+	updater.setLastManifest(responseBody, manifest)
+	return manifest, nil
+}
+
+// fetchManifest returns the body of the manifest and the Content-Type it was
+// served with, if any. unchanged reports whether the body is the cached
+// manifest, the server responding 304 Not Modified.
+func (updater *Updater) fetchManifest(ctx context.Context) ([]byte, string, bool, error) {
+	if updater.config.Source != nil {
+		updater.logger().Debug("Fetching manifest from the source")
+		reader, size, err := updater.config.Source.FetchManifest(ctx)
+		if err != nil {
+			return nil, "", false, err
+		}
+		defer reader.Close()
+
+		limited, err := updater.limitDownload(updater.config.UpdaterConfig, 0, size, reader)
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		body, err := io.ReadAll(updater.newProgress(size).wrap(limited))
+		return body, "", false, err
+	}
 
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(request)
+	manifestUrl, err := joinUrl(updater.config.BaseUrl, updater.config.UpdaterConfig)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
+	}
+	cached := updater.readManifestCache(manifestUrl)
+	updater.logger().Debug("Fetching manifest", "url", redactUrlString(manifestUrl), "cached", cached != nil)
+
+	resp, err := updater.getWithHeader(ctx, func(attempt int) (string, error) {
+		return manifestUrl, nil
+	}, cached.conditionalHeader())
+	if err != nil {
+		return nil, "", false, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Error getting updater config. Status code: %d", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusNotModified {
+		updater.logger().Debug("Manifest not modified, using the cached manifest", "url", redactUrlString(manifestUrl))
+		return cached.Body, cached.ContentType, true, nil
 	}
 
-	responseBody, err := io.ReadAll(resp.Body)
+	limited, err := updater.limitDownload(updater.config.UpdaterConfig, 0, resp.ContentLength, resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
 
-	var manifest UpdaterManifest
-	err = json.Unmarshal(responseBody, &manifest)
+	body, err := io.ReadAll(updater.newProgress(resp.ContentLength).wrap(limited))
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
 
-	return &manifest, nil
+	updater.cacheManifest(manifestUrl, resp, body)
+	return body, resp.Header.Get("Content-Type"), false, nil
+}
+
+// UpdateInfo describes an available update, e.g., to show users what changed
+// before they confirm the update.
+type UpdateInfo struct {
+	Version      string
+	ReleaseNotes string
+	PublishedAt  time.Time
+	Url          string
+	Manifest     *UpdaterManifest
 }
 
 func (updater *Updater) CheckForAvailableUpdate() (bool, string, error) {
+	return updater.CheckForAvailableUpdateContext(context.Background())
+}
+
+func (updater *Updater) CheckForAvailableUpdateContext(ctx context.Context) (bool, string, error) {
+	info, err := updater.CheckForAvailableUpdateInfoContext(ctx)
+	if err != nil || info == nil {
+		return false, "", err
+	}
+
+	return true, info.Version, nil
+}
+
+// CheckForAvailableUpdateInfo is like CheckForAvailableUpdate but describes
+// the update, or returns nil when there is none.
+func (updater *Updater) CheckForAvailableUpdateInfo() (*UpdateInfo, error) {
+	return updater.CheckForAvailableUpdateInfoContext(context.Background())
+}
+
+func (updater *Updater) CheckForAvailableUpdateInfoContext(ctx context.Context) (*UpdateInfo, error) {
+	if info, ok := updater.cachedCheck(); ok {
+		return info, nil
+	}
+
+	updater.setState(StateChecking)
+	updater.emit(Event{Type: EventCheckStarted})
+	ctx, span := updater.startSpan(ctx, "updater.Check", slog.String("updater.current_version", strings.TrimSpace(updater.config.CurrentVersion)))
+	info, err := updater.checkForAvailableUpdate(ctx)
+	if info != nil {
+		span.SetAttributes(slog.String("updater.version", info.Version))
+	}
+	span.End(err)
+	updater.recordCheck(info, err)
+	if err != nil {
+		updater.emit(Event{Type: EventFailed, Err: err})
+		updater.setState(StateFailed)
+		return nil, err
+	}
+
+	updater.saveCheck(info)
+	updater.setState(StateIdle)
+	return info, nil
+}
+
+func (updater *Updater) checkForAvailableUpdate(ctx context.Context) (*UpdateInfo, error) {
 	currentVersion := strings.TrimSpace(updater.config.CurrentVersion)
 	if currentVersion == "" {
-		return false, "", fmt.Errorf("Current version not specified")
+		return nil, fmt.Errorf("Current version not specified")
 	}
 
-	manifest, err := updater.GetManifest()
+	manifest, err := updater.GetManifestContext(ctx)
 	if err != nil {
-		return false, "", err
+		return nil, err
 	}
 
-	manifestVersion := strings.TrimSpace(manifest.Version)
+	manifestVersion, err := updater.constrainedVersion(manifest)
+	if errors.Is(err, ErrVersionConstraint) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest, err = manifest.versionManifest(manifestVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if updater.isNewer(currentVersion, manifestVersion) && updater.inRollout(manifest) && !updater.versionSkipped(manifestVersion) {
+		updater.logger().Info("Update available", "currentVersion", currentVersion, "version", manifestVersion)
+		updater.emit(Event{Type: EventUpdateAvailable, Version: manifestVersion})
+		return newUpdateInfo(manifest), nil
+	}
+
+	updater.logger().Debug("No update available", "currentVersion", currentVersion, "version", manifestVersion)
+	return nil, nil
+}
+
+// isNewer reports whether version is an update over current. Semantic
+// versions must be strictly greater, prereleases only count with
+// AllowPrerelease or on a channel other than the default. Versions that are
+// not semantic versions are compared for inequality.
+func (updater *Updater) isNewer(current string, version string) bool {
+	currentSemver, currentErr := parseVersion(current)
+	semver, err := parseVersion(version)
+	if currentErr != nil || err != nil {
+		return current != version
+	}
 
-	if currentVersion != manifestVersion {
-		return true, manifestVersion, nil
+	if len(semver.prerelease) > 0 && !updater.config.AllowPrerelease && updater.Channel() == "" {
+		return false
 	}
 
-	return false, "", nil
+	return semver.compare(currentSemver) > 0
 }
 
 type variables struct {
+	Version    string
 	Os         string
 	Arch       string
 	ArchiveExt string
 	Ext        string
+	Libc       string
+	CPULevel   string
 }
 
 func (manifest *UpdaterManifest) GetDownloadInfo() (string, string, error) {
+	return manifest.renderDownloadInfo(runtime.GOOS, runtime.GOARCH, detectLibc(), detectCPULevel())
+}
+
+// renderDownloadInfo renders the archive and binary names for the given
+// platform.
+func (manifest *UpdaterManifest) renderDownloadInfo(goos string, goarch string, libc string, cpuLevel string) (string, string, error) {
 	if strings.TrimSpace(manifest.Binary) == "" {
-		return "", "", fmt.Errorf("Manifest does not specify binary name")
+		return "", "", fmt.Errorf("%w. Manifest does not specify binary name", ErrManifestInvalid)
+	}
+
+	variables, err := manifest.platformVariables(goos, goarch, libc, cpuLevel)
+	if err != nil {
+		return "", "", err
+	}
+
+	archiveName := ""
+	if strings.TrimSpace(manifest.Archive) != "" {
+		archiveName, err = renderTemplate("ArchiveTemplate", manifest.Archive, variables)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	binaryName, err := renderTemplate("BinaryTemplate", manifest.Binary, variables)
+	if err != nil {
+		return "", "", err
 	}
 
-	os := runtime.GOOS
+	return archiveName, binaryName, nil
+}
+
+// platformVariables returns the template variables for the given platform.
+// When libc is known, the entries for the cpu level, or a lower level, and
+// libc are preferred, e.g., amd64-v3-musl, then the libc specific entry.
+// Entries without a libc are glibc builds, their cpu level entries are only
+// used on glibc or when libc is unknown, before the plain arch entry.
+func (manifest *UpdaterManifest) platformVariables(goos string, goarch string, libc string, cpuLevel string) (*variables, error) {
+	os := goos
 	archiveExt := ".tar.gz"
-	arch := runtime.GOARCH
+	arch := goarch
 	notSupported := &NotSupportedError{
 		Platform: fmt.Sprintf("%s/%s", os, arch),
 	}
@@ -153,335 +555,989 @@ func (manifest *UpdaterManifest) GetDownloadInfo() (string, string, error) {
 		archiveExt = ".zip"
 		ext = ".exe"
 	}
+	if preferredExt, ok := manifest.ArchiveExt[os]; ok {
+		archiveExt = preferredExt
+	}
 
 	os, ok := manifest.Os[os]
 	if !ok {
-		return "", "", notSupported
+		return nil, notSupported
 	}
 
 	archMap, ok := manifest.Arch[os]
 	if !ok {
-		return "", "", notSupported
+		return nil, notSupported
 	}
 
-	arch, ok = archMap[arch]
+	mappedArch := ""
+	matchedLevel := ""
+	ok = false
+	if libc != "" {
+		for _, level := range cpuLevels(cpuLevel) {
+			mappedArch, ok = archMap[arch+"-"+level+"-"+libc]
+			if ok {
+				matchedLevel = level
+				break
+			}
+		}
+		if !ok {
+			mappedArch, ok = archMap[arch+"-"+libc]
+		}
+	}
+	if !ok && (libc == "" || libc == LibcGlibc) {
+		for _, level := range cpuLevels(cpuLevel) {
+			mappedArch, ok = archMap[arch+"-"+level]
+			if ok {
+				matchedLevel = level
+				break
+			}
+		}
+	}
+	if !ok {
+		mappedArch, ok = archMap[arch]
+	}
 	if !ok {
-		return "", "", notSupported
+		return nil, notSupported
 	}
 
-	variables := variables{
+	return &variables{
+		Version:    strings.TrimSpace(manifest.Version),
 		Os:         os,
-		Arch:       arch,
+		Arch:       mappedArch,
 		ArchiveExt: archiveExt,
 		Ext:        ext,
+		Libc:       libc,
+		CPULevel:   matchedLevel,
+	}, nil
+}
+
+func renderTemplate(name string, text string, variables *variables) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
 	}
 
-	archiveName := ""
-	binaryName := ""
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, variables)
+	if err != nil {
+		return "", err
+	}
 
-	if strings.TrimSpace(manifest.Archive) != "" {
-		archiveTmpl, err := template.New("ArchiveTemplate").Parse(manifest.Archive)
+	return buf.String(), nil
+}
+
+// AllDownloadURLs returns the download url of the archive, or binary if the
+// manifest has no archive, for every platform in the manifest. The map is
+// keyed by "os/arch" using runtime.GOOS and the arch map keys, e.g.,
+// "linux/amd64", "linux/amd64-musl" or "linux/amd64-v3-musl".
+func (manifest *UpdaterManifest) AllDownloadURLs(baseURL string) (map[string]string, error) {
+	urls := make(map[string]string)
+	for goos, os := range manifest.Os {
+		for key := range manifest.Arch[os] {
+			goarch, variant, _ := strings.Cut(key, "-")
+			libc, cpuLevel := variant, ""
+			if level, qualifier, _ := strings.Cut(variant, "-"); cpuLevels(level) != nil {
+				libc, cpuLevel = qualifier, level
+			}
+			archiveName, binaryName, err := manifest.renderDownloadInfo(goos, goarch, libc, cpuLevel)
+			if err != nil {
+				return nil, err
+			}
+
+			name := archiveName
+			if name == "" {
+				name = binaryName
+			}
+
+			downloadUrl, err := joinUrl(baseURL, name)
+			if err != nil {
+				return nil, err
+			}
+			urls[goos+"/"+key] = downloadUrl
+		}
+	}
+
+	return urls, nil
+}
+
+// downloadInfo is the manifest and the archive and binary names resolved for
+// the current platform by a single Update call. version is the version
+// requested with UpdateTo, the empty string for the latest. destName is the
+// name the binary is installed as in the InstallDir, without the .gz
+// extension of gzip compressed binaries.
+type downloadInfo struct {
+	manifest      *UpdaterManifest
+	version       string
+	archiveName   string
+	binaryName    string
+	destName      string
+	migrationName string
+}
+
+func (updater *Updater) resolveDownloadInfo(ctx context.Context, version string) (*downloadInfo, error) {
+	manifest, err := updater.GetManifestContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := version
+	if version == "" {
+		resolved, err = updater.constrainedVersion(manifest)
+	} else {
+		err = updater.checkVersionConstraint(version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err = manifest.versionManifest(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveName, binaryName, err := manifest.GetDownloadInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &downloadInfo{manifest: manifest, version: version, archiveName: archiveName, binaryName: binaryName, destName: binaryName}
+	if archiveName == "" {
+		info.destName = uncompressedName(binaryName)
+	}
+	if updater.config.DestName == "" && strings.TrimSpace(manifest.Migration) == "" {
+		return info, nil
+	}
+
+	variables, err := manifest.platformVariables(runtime.GOOS, runtime.GOARCH, detectLibc(), detectCPULevel())
+	if err != nil {
+		return nil, err
+	}
+
+	if updater.config.DestName != "" {
+		info.destName, err = renderTemplate("DestNameTemplate", updater.config.DestName, variables)
 		if err != nil {
-			return "", "", err
+			return nil, err
 		}
-		var buf bytes.Buffer
-		err = archiveTmpl.Execute(&buf, variables)
+	}
+
+	if strings.TrimSpace(manifest.Migration) != "" {
+		if archiveName == "" {
+			return nil, fmt.Errorf("%w. Manifest specifies a migration but no archive to extract it from", ErrManifestInvalid)
+		}
+		info.migrationName, err = renderTemplate("MigrationTemplate", manifest.Migration, variables)
 		if err != nil {
-			return "", "", err
+			return nil, err
 		}
-		archiveName = buf.String()
 	}
 
-	binaryTmpl, err := template.New("BinaryTemplate").Parse(manifest.Binary)
+	return info, nil
+}
+
+func (info *downloadInfo) alternateUrl(name string, candidate int) (*url.URL, error) {
+	alternates := info.manifest.Urls[name]
+	if candidate < 1 || candidate > len(alternates) {
+		return nil, fmt.Errorf("No alternate url %d for %s", candidate, name)
+	}
+
+	return url.Parse(alternates[candidate-1])
+}
+
+func (updater *Updater) candidateUrl(info *downloadInfo, name string, candidate int) (string, error) {
+	if candidate == 0 {
+		if assetUrl, ok := info.manifest.assetUrls[name]; ok {
+			return assetUrl, nil
+		}
+		return joinUrl(updater.config.BaseUrl, name)
+	}
+
+	alternate, err := info.alternateUrl(name, candidate)
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
-	var buf bytes.Buffer
-	err = binaryTmpl.Execute(&buf, variables)
+	if alternate.Scheme == "ipfs" {
+		return updater.ipfsGatewayUrl(alternate)
+	}
+	if alternate.IsAbs() {
+		return alternate.String(), nil
+	}
+
+	return joinUrl(updater.config.BaseUrl, alternate.String())
+}
+
+func verifyContentAddress(info *downloadInfo, name string, candidate int, path string) error {
+	if candidate == 0 {
+		return nil
+	}
+
+	alternate, err := info.alternateUrl(name, candidate)
 	if err != nil {
-		return "", "", err
+		return err
+	}
+	if alternate.Scheme == "ipfs" {
+		return verifyCid(alternate.Host, path)
 	}
-	binaryName = buf.String()
 
-	return archiveName, binaryName, nil
+	return nil
 }
 
+// downloadUrl returns the url of a download candidate for each attempt. With
+// RefreshManifestOnRetry, info is re-resolved in place before each retry.
+func (updater *Updater) downloadUrl(ctx context.Context, info *downloadInfo, name func() string, candidate int) func(attempt int) (string, error) {
+	return func(attempt int) (string, error) {
+		if attempt > 0 && updater.config.RefreshManifestOnRetry {
+			refreshed, err := updater.resolveDownloadInfo(ctx, info.version)
+			if err != nil {
+				return "", err
+			}
+			*info = *refreshed
+		}
+
+		return updater.candidateUrl(info, name(), candidate)
+	}
+}
+
+// Update downloads and installs the latest version, or returns
+// ErrNoUpdateAvailable when it is not newer than the current version, compared
+// like CheckForAvailableUpdate does. Only one update runs at a time, calls made
+// while an update is in progress return ErrUpdateInProgress.
 func (updater *Updater) Update() error {
-	manifest, err := updater.GetManifest()
+	return updater.UpdateContext(context.Background())
+}
+
+// UpdateContext is Update with a context to cancel the update or set a
+// deadline. Once the new binary is being swapped in, the swap is completed
+// regardless of the context.
+func (updater *Updater) UpdateContext(ctx context.Context) error {
+	return updater.runUpdate(ctx, "", false)
+}
+
+// UpdateTo downloads and installs the given version, the manifest Version or
+// one of its Versions, rather than the latest. Versions that are not listed
+// can be installed when the manifest archive, or binary, name includes
+// {{.Version}}. Versions older than the current version are refused, see
+// Downgrade.
+func (updater *Updater) UpdateTo(version string) error {
+	return updater.UpdateToContext(context.Background(), version)
+}
+
+func (updater *Updater) UpdateToContext(ctx context.Context, version string) error {
+	return updater.runUpdate(ctx, strings.TrimSpace(version), false)
+}
+
+func (updater *Updater) runUpdate(ctx context.Context, version string, downgrade bool) error {
+	if !updater.updateMu.TryLock() {
+		return ErrUpdateInProgress
+	}
+	defer updater.updateMu.Unlock()
+
+	lock, err := updater.lockUpdate()
 	if err != nil {
 		return err
 	}
+	defer lock.unlock()
 
-	archiveName, binaryName, err := manifest.GetDownloadInfo()
+	start := time.Now()
+	ctx, span := updater.startSpan(ctx, "updater.Update", slog.String("updater.current_version", strings.TrimSpace(updater.config.CurrentVersion)))
+	info, err := updater.update(ctx, version, downgrade)
+	if info != nil {
+		span.SetAttributes(slog.String("updater.version", strings.TrimSpace(info.manifest.Version)))
+	}
+	if errors.Is(err, ErrNoUpdateAvailable) {
+		span.End(nil)
+		updater.logger().Info("No update available", "version", strings.TrimSpace(updater.config.CurrentVersion))
+		updater.setState(StateIdle)
+		return err
+	}
+	span.End(err)
+	updater.report(ctx, start, info, err)
+	updater.recordUpdate(start, info, err)
 	if err != nil {
+		if updater.pending != nil {
+			updater.logger().Info("Update deferred", "version", updater.pending.version, "reason", err)
+			updater.setState(StateReadyToInstall)
+		} else {
+			updater.logger().Error("Update failed", "error", err)
+			failed := Event{Type: EventFailed, Version: version, Err: err}
+			if info != nil {
+				failed.Version = strings.TrimSpace(info.manifest.Version)
+			}
+			updater.emit(failed)
+			updater.setState(StateFailed)
+		}
 		return err
 	}
 
-	updater.archiveName = archiveName
-	updater.binaryName = binaryName
+	updater.logger().Info("Update installed",
+		"previousVersion", strings.TrimSpace(updater.config.CurrentVersion),
+		"version", info.manifest.Version,
+		"duration", time.Since(start))
+	updater.emit(Event{Type: EventApplied, Version: strings.TrimSpace(info.manifest.Version)})
+	updater.setState(StateUpdated)
+	return nil
+}
 
-	if archiveName != "" {
-		return updater.downloadArchive()
-	} else {
-		return updater.downloadBinary()
+func (updater *Updater) update(ctx context.Context, version string, downgrade bool) (*downloadInfo, error) {
+	updater.setState(StateChecking)
+	updater.emit(Event{Type: EventCheckStarted, Version: version})
+	if version != "" {
+		err := updater.checkDowngrade(version, downgrade)
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	err := updater.checkPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := updater.resolveDownloadInfo(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	currentVersion := strings.TrimSpace(updater.config.CurrentVersion)
+	manifestVersion := strings.TrimSpace(info.manifest.Version)
+	if version == "" && currentVersion != "" && !updater.isNewer(currentVersion, manifestVersion) {
+		return nil, fmt.Errorf("%w. %s is not newer than the current version %s", ErrNoUpdateAvailable, manifestVersion, currentVersion)
+	}
+	updater.logger().Debug("Selected update",
+		"version", info.manifest.Version,
+		"archive", info.archiveName,
+		"binary", info.binaryName,
+		"dest", info.destName,
+		"migration", info.migrationName)
+
+	return info, updater.applyUpdate(ctx, info)
 }
 
-func (updater *Updater) downloadBinary() error {
-	tempDir := os.TempDir()
-	filename := uuid.NewString()
-	tempFile := filepath.Join(tempDir, filename)
-	binaryPath, err := os.Executable()
+func (updater *Updater) applyUpdate(ctx context.Context, info *downloadInfo) (err error) {
+	err = updater.checkEntitlement(ctx, info.manifest)
 	if err != nil {
 		return err
 	}
 
-	requestUrl, err := url.JoinPath(updater.config.BaseUrl, updater.binaryName)
+	err = checkBuildTime(info.manifest, updater.config.MinBuildTime)
 	if err != nil {
 		return err
 	}
 
-	err = validateUrl(requestUrl)
+	staged := updater.takePending(info.manifest)
+	if staged == nil {
+		staged, err = updater.stage(ctx, info)
+		if err != nil {
+			return err
+		}
+
+		err = updater.verify(staged.binaryPath)
+		if err != nil {
+			staged.remove()
+			return err
+		}
+	}
+	updater.emit(Event{Type: EventVerified, Version: strings.TrimSpace(info.manifest.Version)})
+
+	updater.setState(StateReadyToInstall)
+	err = updater.checkMaintenanceWindow()
+	if err == nil {
+		err = updater.checkPower()
+	}
+	if err == nil {
+		err = updater.waitReadyToSwap(ctx)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = updater.runBeforeApply(info.manifest)
+	}
 	if err != nil {
+		updater.pending = &pendingUpdate{version: info.manifest.Version, staged: staged}
 		return err
 	}
 
-	request, err := http.NewRequest("GET", requestUrl, nil)
+	defer updater.recordApply(time.Now())
+	_, span := updater.startSpan(ctx, "updater.Apply", slog.String("updater.version", strings.TrimSpace(info.manifest.Version)))
+	defer func() {
+		span.End(err)
+	}()
+	var installed *installation
+	if staged.dir {
+		installed, err = updater.installDir(staged)
+	} else {
+		installed, err = updater.install(staged.path, info.destName, updater.binaryMode(staged.mode))
+		if err == nil {
+			installed.migrationPath = staged.migrationPath
+		}
+	}
 	if err != nil {
 		return err
 	}
 
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(request)
+	err = updater.runSmokeTest(installed)
+	if err == nil {
+		err = updater.runHealthCheck(info, installed)
+	}
+	if err == nil {
+		err = updater.runMigration(info, installed)
+	}
+	if err == nil {
+		err = updater.runAfterApply(info.manifest)
+	}
 	if err != nil {
+		updater.logger().Warn("Restoring the previous version", "target", installed.target, "error", err)
+		restoreErr := updater.restore(installed)
+		if restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("Failed to restore the previous binary. %w", restoreErr))
+		}
+		updater.emit(Event{Type: EventRolledBack, Version: strings.TrimSpace(updater.config.CurrentVersion), Err: err})
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Error getting updater config. Status code: %d", resp.StatusCode)
+
+	updater.stateMu.Lock()
+	updater.installedPath = installed.binaryPath
+	updater.stateMu.Unlock()
+
+	retained := updater.retainVersion(installed)
+	if updater.config.RollbackPath != "" {
+		updater.keepBackup(info, installed)
+	} else if !retained {
+		updater.discardBackup(installed)
 	}
+	if updater.config.CacheDir != "" {
+		updater.clearCache()
+	}
+	return nil
+}
 
-	responseBody, err := io.ReadAll(resp.Body)
+func (updater *Updater) runSmokeTest(installed *installation) error {
+	if updater.config.SmokeTest == nil {
+		return nil
+	}
+
+	err := updater.config.SmokeTest(installed.binaryPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w. %w", ErrSmokeTestFailed, err)
+	}
+
+	return nil
+}
+
+// pendingUpdate is an update that was downloaded and verified but not yet
+// installed, kept so a later Update call can install it without downloading
+// it again.
+type pendingUpdate struct {
+	version string
+	staged  *stagedUpdate
+}
+
+func (updater *Updater) takePending(manifest *UpdaterManifest) *stagedUpdate {
+	pending := updater.pending
+	updater.pending = nil
+	if pending == nil {
+		return nil
+	}
+
+	_, err := os.Stat(pending.staged.path)
+	if pending.version != manifest.Version || err != nil {
+		pending.staged.remove()
+		return nil
+	}
+
+	return pending.staged
+}
+
+func (updater *Updater) checkEntitlement(ctx context.Context, manifest *UpdaterManifest) error {
+	if updater.config.Entitlement == nil {
+		return nil
 	}
 
-	err = os.Rename(binaryPath, tempFile)
+	version := strings.TrimSpace(manifest.Version)
+	entitled, err := updater.config.Entitlement(ctx, version)
 	if err != nil {
 		return err
 	}
+	if !entitled {
+		return fmt.Errorf("%w to version %s", ErrNotEntitled, version)
+	}
+
+	return nil
+}
+
+func checkBuildTime(manifest *UpdaterManifest, minBuildTime time.Time) error {
+	buildTime := manifest.BuildTime
+	if minBuildTime.IsZero() || buildTime.IsZero() {
+		return nil
+	}
+
+	if buildTime.Before(minBuildTime) {
+		return fmt.Errorf("%w. Built at %s but the minimum is %s", ErrBuildTooOld, buildTime.Format(time.RFC3339), minBuildTime.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+type stagedUpdate struct {
+	path          string
+	binaryPath    string
+	migrationPath string
+	dir           bool
+	// mode is the mode of the binary in the archive, if any.
+	mode os.FileMode
+}
 
-	err = os.WriteFile(binaryPath, responseBody, 0644)
+func (staged *stagedUpdate) remove() {
+	if staged.dir {
+		os.RemoveAll(staged.path)
+		return
+	}
+
+	if staged.path != "" {
+		os.Remove(staged.path)
+	}
+	if staged.migrationPath != "" {
+		os.Remove(staged.migrationPath)
+	}
+}
+
+func (updater *Updater) stage(ctx context.Context, info *downloadInfo) (*stagedUpdate, error) {
+	// Any failure to patch falls back to the full download.
+	staged, err := updater.stagePatch(ctx, info)
+	if staged != nil {
+		return staged, nil
+	}
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
-		return err
+		updater.logger().Debug("Patch failed, downloading the full update", "error", err)
+	}
+
+	if info.archiveName == "" {
+		stagedPath, err := updater.downloadBinary(ctx, info)
+		if err != nil {
+			return nil, err
+		}
+		return &stagedUpdate{path: stagedPath, binaryPath: stagedPath}, nil
+	}
+
+	if updater.config.InstallDir != "" {
+		return updater.downloadArchiveDir(ctx, info)
+	}
+
+	staged, err = updater.downloadArchive(ctx, info)
+	if errors.Is(err, ErrBinaryNotFoundInArchive) && updater.config.FallbackToBinary {
+		stagedPath, fallbackErr := updater.downloadBinary(ctx, info)
+		if fallbackErr != nil {
+			return nil, errors.Join(err, fallbackErr)
+		}
+		return &stagedUpdate{path: stagedPath, binaryPath: stagedPath}, nil
 	}
-	err = os.Chmod(binaryPath, 0744)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return staged, nil
+}
+
+func (updater *Updater) verify(stagedPath string) error {
+	if updater.config.LinkPolicy != nil && runtime.GOOS == "linux" {
+		err := updater.config.LinkPolicy.check(stagedPath)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (updater *Updater) downloadArchive() error {
+func (updater *Updater) download(ctx context.Context, info *downloadInfo, name func() string) (string, error) {
+	version := strings.TrimSpace(info.manifest.Version)
+	ctx, span := updater.startSpan(ctx, "updater.Download", slog.String("updater.version", version), slog.String("updater.asset.name", name()))
+	tempFile, err := updater.downloadAsset(ctx, info, name)
+	if err == nil {
+		if stat, statErr := os.Stat(tempFile); statErr == nil {
+			span.SetAttributes(slog.Int64("updater.asset.size", stat.Size()))
+		}
+	}
+	span.End(err)
+
+	return tempFile, err
+}
+
+func (updater *Updater) downloadAsset(ctx context.Context, info *downloadInfo, name func() string) (string, error) {
+	if updater.config.CacheDir != "" {
+		tempFile := updater.cachedDownload(ctx, info, name())
+		if tempFile != "" {
+			updater.logger().Debug("Using cached download", "name", name())
+			return tempFile, nil
+		}
+	}
+
+	if size, ok := info.manifest.Sizes[name()]; ok {
+		err := updater.checkDownloadSize(name(), size)
+		if err == nil {
+			err = updater.checkDiskSpace(info, name(), size)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	updater.setState(StateDownloading)
+
+	candidates := 1 + len(info.manifest.Urls[name()])
+	var errs []error
+	for candidate := 0; candidate < candidates; candidate++ {
+		updater.logger().Debug("Downloading", "name", name(), "candidate", candidate)
+		updater.emit(Event{Type: EventDownloadStarted, Version: strings.TrimSpace(info.manifest.Version), Name: name()})
+		tempFile, err := updater.downloadCandidate(ctx, info, name, candidate)
+		if err == nil {
+			updater.logger().Debug("Downloaded", "name", name(), "candidate", candidate)
+			if updater.config.CacheDir != "" {
+				updater.cacheDownload(info, name(), tempFile)
+			}
+			return tempFile, nil
+		}
+		errs = append(errs, err)
+		updater.logger().Warn("Download failed", "name", name(), "candidate", candidate, "error", err)
+		if errors.Is(err, ErrInsufficientDiskSpace) || errors.Is(err, ErrDownloadTooLarge) {
+			break
+		}
+		updater.setState(StateDownloading)
+	}
+
+	return "", errors.Join(errs...)
+}
+
+func (updater *Updater) downloadCandidate(ctx context.Context, info *downloadInfo, name func() string, candidate int) (string, error) {
 	tempDir := os.TempDir()
 	filename := uuid.NewString()
 	tempFile := filepath.Join(tempDir, filename)
 
-	requestUrl, err := url.JoinPath(updater.config.BaseUrl, updater.archiveName)
+	var err error
+	alternate, _ := info.alternateUrl(name(), candidate)
+	if alternate != nil && alternate.Scheme == "oci" {
+		err = updater.downloadOci(ctx, alternate, name(), tempFile)
+	} else if candidate == 0 && updater.config.Source != nil {
+		err = updater.downloadSource(ctx, info, name(), tempFile)
+	} else {
+		err = updater.downloadHttp(ctx, info, name, candidate, tempFile)
+	}
 	if err != nil {
-		return err
+		os.Remove(tempFile)
+		return "", err
 	}
 
-	err = validateUrl(requestUrl)
+	updater.setState(StateVerifying)
+	verifyCtx, span := updater.startSpan(ctx, "updater.Verify", slog.String("updater.asset.name", name()))
+	err = verifyContentAddress(info, name(), candidate, tempFile)
+	if err == nil {
+		err = updater.verifyDownload(verifyCtx, info, name(), tempFile)
+	}
+	span.End(err)
 	if err != nil {
-		return err
+		os.Remove(tempFile)
+		return "", err
 	}
 
-	request, err := http.NewRequest("GET", requestUrl, nil)
+	return tempFile, nil
+}
+
+// downloadHttp downloads into a partial download that survives failures, so
+// that interrupted downloads are resumed, by the next attempt or the next
+// Update call, instead of starting over.
+func (updater *Updater) downloadHttp(ctx context.Context, info *downloadInfo, name func() string, candidate int, destination string) error {
+	partial, err := updater.partialDownload(info, name(), candidate)
 	if err != nil {
 		return err
 	}
+	defer partial.close()
+
+	for attempt := 0; ; attempt++ {
+		interrupted, err := updater.downloadPartial(ctx, info, name, candidate, partial)
+		if err == nil && partial.resumed {
+			// Resumed downloads are always verified as a whole, regardless of
+			// the configured verification, and fail without a checksum.
+			err = verifyManifestChecksum(info.manifest, name(), partial.path)
+			if err != nil {
+				partial.remove()
+				return err
+			}
+		}
+		if err == nil {
+			return partial.finish(destination)
+		}
+		if !interrupted || attempt >= updater.config.MaxRetries {
+			return err
+		}
 
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(request)
-	if err != nil {
-		return err
+		sleepErr := sleep(ctx, updater.retryDelay(attempt+1, nil))
+		if sleepErr != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Error getting updater config. Status code: %d", resp.StatusCode)
+}
+
+func verifyContentDisposition(resp *http.Response, name string) error {
+	header := resp.Header.Get("Content-Disposition")
+	if header == "" {
+		return nil
 	}
 
-	responseBody, err := io.ReadAll(resp.Body)
+	_, params, err := mime.ParseMediaType(header)
 	if err != nil {
-		return err
+		return fmt.Errorf("Invalid Content-Disposition header %q. %w", header, err)
 	}
 
-	err = os.WriteFile(tempFile, responseBody, 0644)
-	if err != nil {
-		return err
+	filename := params["filename"]
+	if filename == "" {
+		return nil
 	}
 
-	if strings.HasSuffix(strings.ToLower(updater.archiveName), ".tar.gz") {
-		return updater.extractTarball(tempFile)
-	} else if strings.HasSuffix(strings.ToLower(updater.archiveName), ".zip") {
-		return updater.extractZip(tempFile)
-	} else {
-		return fmt.Errorf("Error. Only .tar.gz or .zip archives are supported. Got %s", updater.archiveName)
+	if path.Base(filename) != path.Base(name) {
+		return fmt.Errorf("Content-Disposition filename %s does not match the expected %s", filename, path.Base(name))
 	}
+
+	return nil
 }
 
-func (updater *Updater) extractZip(src string) error {
-	tempDir := os.TempDir()
-	destination := filepath.Dir(src)
-	binaryPath, err := os.Executable()
-	if err != nil {
-		return err
+func (updater *Updater) verifyDownload(ctx context.Context, info *downloadInfo, name string, path string) error {
+	if len(info.manifest.Checksums) > 0 {
+		err := verifyManifestChecksum(info.manifest, name, path)
+		if err != nil {
+			return err
+		}
 	}
 
-	uncompressedStream, err := zip.OpenReader(src)
-	if err != nil {
-		return fmt.Errorf("ExtractZip: NewReader failed %w", err)
+	if updater.config.PinnedKeyPath != "" {
+		err := updater.verifySignature(ctx, info, name, path)
+		if err != nil {
+			return err
+		}
 	}
 
-	created := false
+	if len(updater.config.SigningKeys) > 0 {
+		err := updater.verifyArtifactSignatures(ctx, info, name, path)
+		if err != nil {
+			return err
+		}
+	}
 
-	for _, f := range uncompressedStream.File {
-		rc, err := f.Open()
+	if updater.config.MinisignPublicKey != "" {
+		err := updater.verifyArtifactMinisign(ctx, info, name, path)
 		if err != nil {
-			return fmt.Errorf("ExtractZip: failed to open file %w", err)
-		}
-
-		basename := filepath.Base(f.Name)
-
-		if basename == updater.binaryName {
-			filename := uuid.NewString()
-			tempName := uuid.NewString()
-			tempPath := filepath.Join(tempDir, tempName)
-
-			path := filepath.Join(destination, filename)
-
-			if f.FileInfo().Mode().IsRegular() {
-				file, err := os.Create(path)
-				if err != nil {
-					return fmt.Errorf("ExtractZip: failed to open file %w", err)
-				}
-
-				_, err = io.Copy(file, rc)
-				if err != nil {
-					return fmt.Errorf("ExtractZip: failed to copy file %w", err)
-				}
-				err = file.Close()
-				if err != nil {
-					return err
-				}
-				rc.Close()
-				err = os.Rename(binaryPath, tempPath)
-				if err != nil {
-					return err
-				}
-
-				err = os.Rename(path, binaryPath)
-				if err != nil {
-					return err
-				}
-				err = os.Chmod(binaryPath, 0744)
-				if err != nil {
-					return err
-				}
-				created = true
-				break
-			}
+			return err
 		}
 	}
 
-	if !created {
-		return fmt.Errorf("Error extracting binary from %s. No binary matched the name %s", updater.archiveName, updater.binaryName)
+	if updater.config.TransparencyLogKey != "" {
+		err := updater.verifyTransparencyLog(ctx, info, name, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if updater.config.JwsKey != nil {
+		err := updater.verifyJws(info.manifest, name, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(updater.config.AllowedChecksums) > 0 {
+		err := verifyAllowedChecksum(name, path, updater.config.AllowedChecksums)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (updater *Updater) extractTarball(src string) error {
-	tempDir := os.TempDir()
-	destination := filepath.Dir(src)
-	binaryPath, err := os.Executable()
+func (updater *Updater) downloadBinary(ctx context.Context, info *downloadInfo) (string, error) {
+	tempFile, err := updater.download(ctx, info, func() string { return info.binaryName })
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	file, err := os.Open(src)
-	if err != nil {
-		return err
+	if isGzipBinary(info.binaryName) {
+		_, span := updater.startSpan(ctx, "updater.Extract", slog.String("updater.asset.name", info.binaryName))
+		binaryPath, err := updater.decompressBinary(info.binaryName, tempFile)
+		span.End(err)
+		return binaryPath, err
 	}
 
-	uncompressedStream, err := gzip.NewReader(file)
+	return tempFile, nil
+}
+
+func (updater *Updater) downloadArchive(ctx context.Context, info *downloadInfo) (*stagedUpdate, error) {
+	tempFile, err := updater.download(ctx, info, func() string { return info.archiveName })
 	if err != nil {
-		return fmt.Errorf("ExtractTarGz: NewReader failed %w", err)
+		return nil, err
 	}
 
-	tarReader := tar.NewReader(uncompressedStream)
+	_, span := updater.startSpan(ctx, "updater.Extract", slog.String("updater.asset.name", info.archiveName))
+	var staged *stagedUpdate
+	if isTarball(info.archiveName) {
+		staged, err = updater.extractTarball(info, tempFile)
+	} else if isZip(info.archiveName) {
+		staged, err = updater.extractZip(info, tempFile)
+	} else {
+		os.Remove(tempFile)
+		err = unsupportedArchive(info.archiveName)
+	}
+	span.End(err)
 
-	created := false
+	return staged, err
+}
 
-	for {
-		header, err := tarReader.Next()
+func (updater *Updater) targetPath(binaryName string) (string, error) {
+	if updater.config.TargetPath != "" {
+		return updater.config.TargetPath, nil
+	}
 
-		if err == io.EOF {
-			break
+	if updater.config.InstallDir != "" {
+		return filepath.Join(updater.config.InstallDir, binaryName), nil
+	}
+
+	return os.Executable()
+}
+
+// installation describes an installed update. backupPath holds the previous
+// binary, or install directory, until the update is known to be good.
+type installation struct {
+	target        string
+	binaryPath    string
+	backupPath    string
+	migrationPath string
+	dir           bool
+	// elevated reports whether the binary was replaced with elevated
+	// privileges, which restoring it requires as well.
+	elevated bool
+}
+
+const defaultBinaryMode os.FileMode = 0755
+
+// binaryMode returns the mode of the installed binary, BinaryMode or else the
+// mode of the binary in the archive when it is executable, defaulting to
+// 0755.
+func (updater *Updater) binaryMode(archiveMode os.FileMode) os.FileMode {
+	if updater.config.BinaryMode != 0 {
+		return updater.config.BinaryMode.Perm()
+	}
+	if archiveMode.Perm()&0111 != 0 {
+		return archiveMode.Perm()
+	}
+
+	return defaultBinaryMode
+}
+
+func (updater *Updater) install(stagedPath string, binaryName string, mode os.FileMode) (*installation, error) {
+	binaryPath, err := updater.targetPath(binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updater.recoverSwap(binaryPath)
+	if err != nil {
+		os.Remove(stagedPath)
+		return nil, err
+	}
+
+	installed := &installation{target: binaryPath, binaryPath: binaryPath}
+	_, err = os.Stat(binaryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if updater.config.MissingTarget != MissingTargetCreate {
+			os.Remove(stagedPath)
+			return nil, fmt.Errorf("Target binary %s does not exist. %w", binaryPath, err)
+		}
+		err = os.MkdirAll(filepath.Dir(binaryPath), 0755)
+		if err != nil {
+			return nil, err
 		}
+	} else if err != nil {
+		return nil, err
+	} else {
+		if runtime.GOOS == "windows" && updater.config.WindowsCleanupStrategy != CleanupLeave {
+			// Binaries left behind by earlier updates, best effort.
+			_ = updater.cleanupOldBinaries(binaryPath)
+		}
+		installed.backupPath = updater.backupPath(binaryPath)
+	}
 
+	// The new binary is written next to the target first, to be renamed into
+	// place on the same volume.
+	tempPath := filepath.Join(filepath.Dir(binaryPath), "."+filepath.Base(binaryPath)+"."+uuid.NewString()+".new")
+	err = moveFile(stagedPath, tempPath)
+	if permissionDenied(err) && installed.backupPath != "" {
+		err = updater.installElevated(stagedPath, installed, mode, err)
 		if err != nil {
-			return fmt.Errorf("ExtractTarGz: Next() failed: %w", err)
-		}
-
-		basename := filepath.Base(header.Name)
-
-		if basename == updater.binaryName {
-			filename := uuid.NewString()
-			tempName := uuid.NewString()
-			tempPath := filepath.Join(tempDir, tempName)
-
-			path := filepath.Join(destination, filename)
-
-			switch header.Typeflag {
-			case tar.TypeReg:
-				outFile, err := os.Create(path)
-				if err != nil {
-					return fmt.Errorf("ExtractTarGz: Create() failed: %w", err)
-				}
-				if _, err := io.Copy(outFile, tarReader); err != nil {
-					return fmt.Errorf("ExtractTarGz: Copy() failed: %w", err)
-				}
-				err = outFile.Close()
-				if err != nil {
-					return fmt.Errorf("Failed to close file. %w", err)
-				}
-				err = os.Rename(binaryPath, tempPath)
-				if err != nil {
-					return err
-				}
-				err = os.Rename(path, binaryPath)
-				if err != nil {
-					return err
-				}
-				err = os.Chmod(binaryPath, 0744)
-				if err != nil {
-					return err
-				}
-				created = true
-				break
-			default:
-				continue
-			}
+			os.Remove(stagedPath)
+			return nil, err
+		}
+		return installed, nil
+	}
+	if err != nil {
+		os.Remove(stagedPath)
+		return nil, err
+	}
+
+	updater.logger().Debug("Installing binary", "target", binaryPath, "backup", installed.backupPath, "mode", mode)
+	err = os.Chmod(tempPath, mode)
+	if err == nil {
+		err = syncFile(tempPath)
+	}
+	if err == nil {
+		err = updater.swapBinary(tempPath, binaryPath, installed.backupPath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, err
+	}
+
+	return installed, nil
+}
+
+func (updater *Updater) restore(installed *installation) error {
+	if installed.elevated {
+		return elevatedRestore(installed.backupPath, installed.target)
+	}
+
+	// The backup replaces the binary in a single rename.
+	if !installed.dir && installed.backupPath != "" {
+		return moveFile(installed.backupPath, installed.target)
+	}
+
+	if installed.dir {
+		err := os.RemoveAll(installed.target)
+		if err != nil {
+			return err
 		}
+	} else {
+		err := os.Remove(installed.target)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if installed.backupPath == "" {
+		return nil
+	}
+
+	return os.Rename(installed.backupPath, installed.target)
+}
 
+func (updater *Updater) discardBackup(installed *installation) {
+	if installed.backupPath == "" || updater.isRetained(installed.backupPath) {
+		return
 	}
 
-	if !created {
-		return fmt.Errorf("Error extracting binary from %s. No binary matched the name %s", updater.archiveName, updater.binaryName)
+	if installed.dir {
+		os.RemoveAll(installed.backupPath)
+		return
 	}
 
-	return os.Remove(src)
+	// The update is installed at this point, failing to schedule the removal
+	// of the previous binary only leaves it behind.
+	_ = updater.cleanupBackup(installed.backupPath)
 }
@@ -0,0 +1,170 @@
+package updatertest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dworthen/updater"
+)
+
+const BaseUrl = "https://updates.updatertest.invalid"
+
+type Transport struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	requests []string
+}
+
+func NewTransport() *Transport {
+	return &Transport{
+		files: make(map[string][]byte),
+	}
+}
+
+func (transport *Transport) AddFile(name string, data []byte) {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	transport.files[strings.TrimPrefix(name, "/")] = data
+}
+
+func (transport *Transport) RemoveFile(name string) {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	delete(transport.files, strings.TrimPrefix(name, "/"))
+}
+
+func (transport *Transport) AddManifest(name string, manifest *updater.UpdaterManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	transport.AddFile(name, data)
+	return nil
+}
+
+func (transport *Transport) Requests() []string {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	return append([]string(nil), transport.requests...)
+}
+
+func (transport *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.mu.Lock()
+	transport.requests = append(transport.requests, request.URL.String())
+	data, ok := transport.files[strings.TrimPrefix(request.URL.Path, "/")]
+	transport.mu.Unlock()
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+		data = []byte(http.StatusText(status))
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/octet-stream"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       request,
+	}, nil
+}
+
+func Manifest(version string, binary string) *updater.UpdaterManifest {
+	return &updater.UpdaterManifest{
+		Version: version,
+		Binary:  binary,
+		Os: map[string]string{
+			runtime.GOOS: runtime.GOOS,
+		},
+		Arch: map[string]map[string]string{
+			runtime.GOOS: {
+				runtime.GOARCH: runtime.GOARCH,
+			},
+		},
+	}
+}
+
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TarGz(files map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, name := range sortedNames(files) {
+		data := files[name]
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0755,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = tarWriter.Write(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func Zip(files map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+
+	for _, name := range sortedNames(files) {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = writer.Write(files[name])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := zipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
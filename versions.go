@@ -0,0 +1,206 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RetainedVersion is a binary replaced by an update and kept in the
+// VersionsDir, e.g., to roll back to manually.
+type RetainedVersion struct {
+	Version    string
+	Path       string
+	ReplacedAt time.Time
+}
+
+// versionsDir defaults to a versions directory next to the StorePath.
+func (updater *Updater) versionsDir() (string, error) {
+	if updater.config.VersionsDir != "" {
+		return updater.config.VersionsDir, nil
+	}
+
+	path, err := updater.storePath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), "versions"), nil
+}
+
+// retainedName names retained versions of the binary <name>-<version><ext>,
+// e.g., myapp-1.2.3.exe.
+func retainedName(binaryPath string, version string) string {
+	base := filepath.Base(binaryPath)
+	ext := filepath.Ext(base)
+	version = strings.NewReplacer("/", "_", "\\", "_").Replace(version)
+
+	return strings.TrimSuffix(base, ext) + "-" + version + ext
+}
+
+func (updater *Updater) isRetained(path string) bool {
+	dir, err := updater.versionsDir()
+	if err != nil {
+		return false
+	}
+
+	return filepath.Dir(filepath.Clean(path)) == filepath.Clean(dir)
+}
+
+// retainVersion moves the backup of the replaced binary to the VersionsDir,
+// labeled with the current version, and prunes the oldest retained versions.
+// It reports whether the backup was retained, failing to retain it only means
+// the backup is discarded as usual.
+func (updater *Updater) retainVersion(installed *installation) bool {
+	if updater.config.KeepVersions <= 0 || installed.dir || installed.elevated || installed.backupPath == "" {
+		return false
+	}
+
+	dir, err := updater.versionsDir()
+	if err != nil {
+		return false
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return false
+	}
+
+	retainedPath := filepath.Join(dir, retainedName(installed.target, strings.TrimSpace(updater.config.CurrentVersion)))
+	os.Remove(retainedPath)
+	err = moveFile(installed.backupPath, retainedPath)
+	if err != nil {
+		return false
+	}
+	installed.backupPath = retainedPath
+
+	now := time.Now()
+	_ = os.Chtimes(retainedPath, now, now)
+	_ = updater.cleanupOldVersions(installed.target, updater.config.KeepVersions)
+	return true
+}
+
+// retainedTarget returns the binary whose versions are retained, the target
+// of retainVersion. With an InstallDir, the binary name comes from the last
+// update or else from the manifest.
+func (updater *Updater) retainedTarget() (string, error) {
+	if updater.config.TargetPath != "" || updater.config.InstallDir == "" {
+		return updater.targetPath("")
+	}
+
+	updater.stateMu.Lock()
+	installedPath := updater.installedPath
+	updater.stateMu.Unlock()
+	if installedPath != "" {
+		return installedPath, nil
+	}
+
+	info, err := updater.resolveDownloadInfo(context.Background(), "")
+	if err != nil {
+		return "", err
+	}
+
+	return updater.targetPath(info.destName)
+}
+
+// RetainedVersions lists the versions of the binary retained in the
+// VersionsDir with KeepVersions, most recently replaced first. With an
+// InstallDir and no update installed by this Updater, the manifest is fetched
+// to know the name of the binary.
+func (updater *Updater) RetainedVersions() ([]RetainedVersion, error) {
+	binaryPath, err := updater.retainedTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	return updater.retainedVersions(binaryPath)
+}
+
+// retainedVersions lists the files named like retainedName(binaryPath) with a
+// version that parses, leaving out other binaries sharing the name, e.g.,
+// myapp-helper-1.0.0 for myapp.
+func (updater *Updater) retainedVersions(binaryPath string) ([]RetainedVersion, error) {
+	dir, err := updater.versionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(binaryPath)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	var versions []RetainedVersion
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) || len(name) <= len(prefix)+len(ext) {
+			continue
+		}
+		version := name[len(prefix) : len(name)-len(ext)]
+		if _, err := parseVersion(version); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		versions = append(versions, RetainedVersion{
+			Version:    version,
+			Path:       filepath.Join(dir, name),
+			ReplacedAt: info.ModTime(),
+		})
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].ReplacedAt.After(versions[j].ReplacedAt)
+	})
+
+	return versions, nil
+}
+
+// CleanupOldVersions removes all but the keep most recently replaced versions
+// retained in the VersionsDir. The backup recorded for Rollback is kept
+// regardless.
+func (updater *Updater) CleanupOldVersions(keep int) error {
+	binaryPath, err := updater.retainedTarget()
+	if err != nil {
+		return err
+	}
+
+	return updater.cleanupOldVersions(binaryPath, keep)
+}
+
+func (updater *Updater) cleanupOldVersions(binaryPath string, keep int) error {
+	versions, err := updater.retainedVersions(binaryPath)
+	if err != nil {
+		return err
+	}
+	if keep < 0 {
+		keep = 0
+	}
+
+	kept := updater.rollbackBackup()
+	for i := keep; i < len(versions); i++ {
+		if versions[i].Path == kept {
+			continue
+		}
+
+		err := os.Remove(versions[i].Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}